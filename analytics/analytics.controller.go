@@ -0,0 +1,91 @@
+package analytics
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type Controller struct {
+	service *Service
+}
+
+func NewController(service *Service) *Controller {
+	return &Controller{service: service}
+}
+
+func (c *Controller) RegisterAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/admin/analytics/games", c.GetGameStats)
+	r.GET("/admin/analytics/games/buckets", c.GetGameStatsByBucket)
+	r.POST("/admin/analytics/games/refresh", c.Refresh)
+}
+
+func (c *Controller) GetGameStats(ctx *gin.Context) {
+	var filter GameStatsFilter
+	if !bindGameStatsFilter(ctx, &filter) {
+		return
+	}
+
+	stats, err := c.service.GetGameStats(&filter)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+func (c *Controller) GetGameStatsByBucket(ctx *gin.Context) {
+	var filter GameStatsFilter
+	if !bindGameStatsFilter(ctx, &filter) {
+		return
+	}
+
+	stats, err := c.service.GetGameStatsByBucket(&filter)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// bindGameStatsFilter binds the stats query, replying 400 if it is invalid
+func bindGameStatsFilter(ctx *gin.Context, filter *GameStatsFilter) bool {
+	if err := ctx.ShouldBindQuery(filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter"})
+		return false
+	}
+
+	if raw := ctx.Query("game_id"); raw != "" {
+		gameID, err := uuid.Parse(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+			return false
+		}
+		filter.GameID = &gameID
+	}
+	return true
+}
+
+func (c *Controller) Refresh(ctx *gin.Context) {
+	var req RefreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := c.service.Refresh(req.From); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "rollups refreshed"})
+}
+
+func handleError(ctx *gin.Context, err error) {
+	switch err {
+	case ErrInvalidBucket, ErrInvalidDateRange:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
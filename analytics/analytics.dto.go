@@ -0,0 +1,36 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type GameStatsFilter struct {
+	From     *time.Time `form:"from" time_format:"2006-01-02"`
+	To       *time.Time `form:"to" time_format:"2006-01-02"`
+	Category *string    `form:"category"`
+	GameID   *uuid.UUID `form:"-"`                  // parsed by the controller, gin can't bind uuid.UUID
+	Bucket   string     `form:"bucket,default=day"` // day, week or month
+}
+
+type RefreshRequest struct {
+	From time.Time `json:"from" binding:"required"`
+}
+
+// GameStats holds aggregated figures for one game, optionally within a time bucket.
+// RTP values are percentages, as is HouseEdge (see models.Game).
+type GameStats struct {
+	GameID        uuid.UUID  `json:"game_id"`
+	GameName      string     `json:"game_name"`
+	Category      string     `json:"category"`
+	Bucket        *time.Time `json:"bucket,omitempty"`
+	Rounds        int64      `json:"rounds"`
+	UniquePlayers int64      `json:"unique_players"`
+	Handle        float64    `json:"handle"`
+	Payouts       float64    `json:"payouts"`
+	GGR           float64    `json:"ggr"`
+	RTP           float64    `json:"rtp"`
+	HouseEdge     float64    `json:"house_edge"`
+	ExpectedRTP   float64    `json:"expected_rtp"`
+}
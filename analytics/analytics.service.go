@@ -0,0 +1,152 @@
+package analytics
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gamba/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidBucket    = errors.New("invalid bucket, expected day, week or month")
+	ErrInvalidDateRange = errors.New("from must not be after to")
+)
+
+// Default reporting window when no date range is given
+const defaultRange = 30 * 24 * time.Hour
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// RunRollups rebuilds the rollups on start and then keeps recent days fresh
+func (s *Service) RunRollups(interval time.Duration) {
+	var last time.Time
+	s.db.Model(&models.GamePlayerDailyStat{}).Select("COALESCE(MAX(day), '1970-01-01')").Scan(&last)
+	if err := s.Refresh(last); err != nil {
+		log.Printf("Error refreshing game rollups: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Re-aggregate yesterday too so rounds straddling midnight are counted
+		from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+		if err := s.Refresh(from); err != nil {
+			log.Printf("Error refreshing game rollups: %v", err)
+		}
+	}
+}
+
+// Refresh re-aggregates game bets into daily per-player rollups from the given day onwards
+func (s *Service) Refresh(from time.Time) error {
+	from = from.UTC().Truncate(24 * time.Hour)
+
+	return s.db.Exec(`
+		INSERT INTO game_player_daily_stats (game_id, user_id, day, rounds, handle, payouts, updated_at)
+		SELECT game_id, user_id, (created_at AT TIME ZONE 'UTC')::date, COUNT(*), SUM(amount), SUM(payout), NOW()
+		FROM bets
+		WHERE type = ? AND game_id IS NOT NULL AND deleted_at IS NULL AND created_at >= ?
+		GROUP BY game_id, user_id, (created_at AT TIME ZONE 'UTC')::date
+		ON CONFLICT (game_id, user_id, day) DO UPDATE SET
+			rounds = EXCLUDED.rounds,
+			handle = EXCLUDED.handle,
+			payouts = EXCLUDED.payouts,
+			updated_at = EXCLUDED.updated_at`,
+		models.BetTypeGame, from,
+	).Error
+}
+
+// GetGameStats returns totals per game over the filtered range
+func (s *Service) GetGameStats(filter *GameStatsFilter) ([]GameStats, error) {
+	query, err := s.statsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []GameStats
+	if err := query.
+		Select(statsColumns).
+		Group("s.game_id, g.name, g.category, g.house_edge").
+		Order("handle DESC").
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	return withDerived(stats), nil
+}
+
+// GetGameStatsByBucket returns figures per game and time bucket over the filtered range
+func (s *Service) GetGameStatsByBucket(filter *GameStatsFilter) ([]GameStats, error) {
+	if filter.Bucket != "day" && filter.Bucket != "week" && filter.Bucket != "month" {
+		return nil, ErrInvalidBucket
+	}
+
+	query, err := s.statsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []GameStats
+	if err := query.
+		Select("date_trunc(?, s.day) AS bucket, "+statsColumns, filter.Bucket).
+		Group("bucket, s.game_id, g.name, g.category, g.house_edge").
+		Order("bucket ASC, handle DESC").
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	return withDerived(stats), nil
+}
+
+const statsColumns = `s.game_id, g.name AS game_name, g.category, g.house_edge,
+	SUM(s.rounds) AS rounds, COUNT(DISTINCT s.user_id) AS unique_players,
+	SUM(s.handle) AS handle, SUM(s.payouts) AS payouts`
+
+// statsQuery applies the shared date range and game filters to the rollup table
+func (s *Service) statsQuery(filter *GameStatsFilter) (*gorm.DB, error) {
+	to := time.Now().UTC()
+	if filter.To != nil {
+		to = *filter.To
+	}
+	from := to.Add(-defaultRange)
+	if filter.From != nil {
+		from = *filter.From
+	}
+	if from.After(to) {
+		return nil, ErrInvalidDateRange
+	}
+
+	query := s.db.Table("game_player_daily_stats AS s").
+		Joins("JOIN games g ON g.id = s.game_id").
+		Where("s.day >= ? AND s.day <= ?", from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	if filter.Category != nil {
+		query = query.Where("g.category = ?", *filter.Category)
+	}
+	if filter.GameID != nil {
+		query = query.Where("s.game_id = ?", *filter.GameID)
+	}
+
+	return query, nil
+}
+
+// withDerived fills in GGR and RTP figures from the summed columns
+func withDerived(stats []GameStats) []GameStats {
+	for i := range stats {
+		stats[i].GGR = stats[i].Handle - stats[i].Payouts
+		if stats[i].Handle > 0 {
+			stats[i].RTP = stats[i].Payouts / stats[i].Handle * 100
+		}
+		stats[i].ExpectedRTP = 100 - stats[i].HouseEdge
+	}
+	return stats
+}
@@ -23,7 +23,7 @@ func NewService(db *gorm.DB) *Service {
 
 func (s *Service) GetByID(id, userID uuid.UUID, isAdmin bool) (*models.Bet, error) {
 	var bet models.Bet
-	if err := s.db.First(&bet, "id = ?", id).Error; err != nil {
+	if err := s.db.Preload("Legs").First(&bet, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrBetNotFound
 		}
@@ -39,7 +39,7 @@ func (s *Service) GetByID(id, userID uuid.UUID, isAdmin bool) (*models.Bet, erro
 
 func (s *Service) GetUserBets(userID uuid.UUID, filter *BetFilter) ([]models.Bet, error) {
 	var bets []models.Bet
-	query := s.db.Where("user_id = ?", userID)
+	query := s.db.Preload("Legs").Where("user_id = ?", userID)
 
 	if filter.Type != nil {
 		query = query.Where("type = ?", *filter.Type)
@@ -67,3 +67,8 @@ type WSReadEvent struct {
 	ChatID    uuid.UUID `json:"chat_id"`
 	MessageID uuid.UUID `json:"message_id"`
 }
+
+// WSSubscribeEvent subscribes to or unsubscribes from a topic such as "event:<id>"
+type WSSubscribeEvent struct {
+	Topic string `json:"topic"`
+}
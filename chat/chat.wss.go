@@ -3,6 +3,7 @@ package chat
 import (
 	"encoding/json"
 	"log"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
@@ -19,9 +20,11 @@ type Client struct {
 
 type Hub struct {
 	clients    map[uuid.UUID]map[*Client]bool // userID -> clients
+	topics     map[string]map[*Client]bool    // topic -> subscribed clients
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan *BroadcastMessage
+	publish    chan *TopicMessage
 	mu         sync.RWMutex
 }
 
@@ -30,12 +33,19 @@ type BroadcastMessage struct {
 	Message []byte
 }
 
+type TopicMessage struct {
+	Topic   string
+	Message []byte
+}
+
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[uuid.UUID]map[*Client]bool),
+		topics:     make(map[string]map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan *BroadcastMessage, 256),
+		publish:    make(chan *TopicMessage, 256),
 	}
 }
 
@@ -62,6 +72,12 @@ func (h *Hub) Run() {
 					}
 				}
 			}
+			for topic, subscribers := range h.topics {
+				delete(subscribers, client)
+				if len(subscribers) == 0 {
+					delete(h.topics, topic)
+				}
+			}
 			h.mu.Unlock()
 			log.Printf("Client disconnected: user=%s", client.UserID)
 
@@ -78,6 +94,17 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.RUnlock()
+
+		case msg := <-h.publish:
+			h.mu.RLock()
+			for client := range h.topics[msg.Topic] {
+				select {
+				case client.Send <- msg.Message:
+				default:
+					// Slow subscriber, drop the update rather than block the hub
+				}
+			}
+			h.mu.RUnlock()
 		}
 	}
 }
@@ -95,6 +122,54 @@ func (h *Hub) SendToUser(userID uuid.UUID, msg WSMessage) {
 	}
 }
 
+// Publish sends a message to every client subscribed to the topic
+func (h *Hub) Publish(topic string, msg WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	h.publish <- &TopicMessage{
+		Topic:   topic,
+		Message: data,
+	}
+}
+
+// Subscribe adds a client to a topic
+func (h *Hub) Subscribe(client *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Client]bool)
+	}
+	h.topics[topic][client] = true
+}
+
+// Unsubscribe removes a client from a topic
+func (h *Hub) Unsubscribe(client *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if subscribers, ok := h.topics[topic]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// TopicUsers returns the users with a client subscribed to the topic
+func (h *Hub) TopicUsers(topic string) map[uuid.UUID]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	users := make(map[uuid.UUID]bool, len(h.topics[topic]))
+	for client := range h.topics[topic] {
+		users[client.UserID] = true
+	}
+	return users
+}
+
 // Register registers a client
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -156,6 +231,10 @@ func (c *Client) ReadPump(service *Service) {
 			c.handleTyping(service, wsMsg.Payload)
 		case "mark_read":
 			c.handleMarkRead(service, wsMsg.Payload)
+		case "subscribe":
+			c.handleSubscribe(wsMsg.Payload, true)
+		case "unsubscribe":
+			c.handleSubscribe(wsMsg.Payload, false)
 		}
 	}
 }
@@ -215,3 +294,31 @@ func (c *Client) handleMarkRead(service *Service, payload interface{}) {
 		service.MarkChatAsRead(event.ChatID, c.UserID)
 	}
 }
+
+func (c *Client) handleSubscribe(payload interface{}, subscribe bool) {
+	data, _ := json.Marshal(payload)
+	var event WSSubscribeEvent
+	if err := json.Unmarshal(data, &event); err != nil || !validTopic(event.Topic) {
+		return
+	}
+
+	if subscribe {
+		c.Hub.Subscribe(c, event.Topic)
+	} else {
+		c.Hub.Unsubscribe(c, event.Topic)
+	}
+}
+
+// topicPrefixes lists the kinds of topic a client may subscribe to
+var topicPrefixes = []string{"event:", "tournament:"}
+
+// validTopic reports whether topic is a known prefix followed by a UUID
+func validTopic(topic string) bool {
+	for _, prefix := range topicPrefixes {
+		if id, ok := strings.CutPrefix(topic, prefix); ok {
+			_, err := uuid.Parse(id)
+			return err == nil
+		}
+	}
+	return false
+}
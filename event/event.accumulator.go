@@ -0,0 +1,285 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxAccumulatorLegs = 20
+
+// PlaceAccumulator places a single bet over outcomes on several events.
+// Combined odds are the product of the leg odds.
+func (s *Service) PlaceAccumulator(userID uuid.UUID, req *PlaceAccumulatorRequest) (*models.Bet, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if len(req.Legs) < 2 {
+		return nil, ErrTooFewLegs
+	}
+	if len(req.Legs) > maxAccumulatorLegs {
+		return nil, ErrTooManyLegs
+	}
+
+	legs := make([]models.BetLeg, 0, len(req.Legs))
+	seen := make(map[uuid.UUID]bool)
+	outcomes := make([]models.EventOutcome, 0, len(req.Legs))
+	odds := 1.0
+
+	for _, l := range req.Legs {
+		if seen[l.EventID] {
+			return nil, ErrDuplicateLegEvent
+		}
+		seen[l.EventID] = true
+
+		var event models.Event
+		if err := s.db.First(&event, "id = ?", l.EventID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrEventNotFound
+			}
+			return nil, err
+		}
+		if !s.isBettable(&event) {
+			return nil, ErrEventNotBettable
+		}
+		if event.Mode == models.EventModePool {
+			return nil, ErrPoolBetUnsupported
+		}
+
+		var outcome models.EventOutcome
+		if err := s.db.First(&outcome, "id = ? AND event_id = ?", l.OutcomeID, l.EventID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrOutcomeNotFound
+			}
+			return nil, err
+		}
+		if outcome.IsSuspended {
+			return nil, ErrOutcomeSuspended
+		}
+		if l.OddsVersion != nil && *l.OddsVersion != outcome.OddsVersion {
+			return nil, ErrOddsChanged
+		}
+		if err := s.checkMarketOpen(s.db, outcome.MarketID); err != nil {
+			return nil, err
+		}
+
+		odds *= outcome.Odds
+		outcomes = append(outcomes, outcome)
+		legs = append(legs, models.BetLeg{
+			ID:          uuid.New(),
+			EventID:     l.EventID,
+			OutcomeID:   l.OutcomeID,
+			Odds:        outcome.Odds,
+			OddsVersion: outcome.OddsVersion,
+			Status:      models.BetLegStatusPending,
+		})
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	if user.Balance < req.Amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	var bet *models.Bet
+	actions := make([]liabilityAction, len(outcomes))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("balance", user.Balance-req.Amount).Error; err != nil {
+			return err
+		}
+
+		bet = &models.Bet{
+			ID:     uuid.New(),
+			UserID: userID,
+			Type:   models.BetTypeAccumulator,
+			Amount: req.Amount,
+			Odds:   odds,
+			Status: models.BetStatusPending,
+		}
+		if err := tx.Create(bet).Error; err != nil {
+			return err
+		}
+
+		for i := range legs {
+			legs[i].BetID = bet.ID
+		}
+		if err := tx.Create(&legs).Error; err != nil {
+			return err
+		}
+		bet.Legs = legs
+
+		transaction := models.Transaction{
+			ID:            uuid.New(),
+			UserID:        userID,
+			Type:          models.TransactionTypeBet,
+			Status:        models.TransactionStatusCompleted,
+			Amount:        -req.Amount,
+			ReferenceID:   &bet.ID,
+			ReferenceType: strPtr("bet"),
+			Description:   fmt.Sprintf("Accumulator bet (%d legs)", len(legs)),
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		for i := range outcomes {
+			action, err := s.enforceLiability(tx, &outcomes[i])
+			if err != nil {
+				return err
+			}
+			actions[i] = action
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range outcomes {
+		s.publishLiability(&outcomes[i], actions[i])
+	}
+
+	return bet, nil
+}
+
+// settleLegs resolves pending accumulator legs and then settles any parent
+// bets whose legs are all known. Legs without a result are voided.
+func (s *Service) settleLegs(tx *gorm.DB, legs []models.BetLeg, results map[uuid.UUID]outcomeResult) error {
+	now := time.Now()
+	betIDs := make(map[uuid.UUID]bool)
+
+	for _, leg := range legs {
+		r, ok := results[leg.OutcomeID]
+		if !ok {
+			r = outcomeResult{status: models.BetLegStatusVoid}
+		}
+
+		updates := map[string]interface{}{
+			"status":     r.status,
+			"settled_at": now,
+		}
+		switch r.status {
+		case models.BetLegStatusWon:
+			updates["dead_heat_factor"] = r.factor
+		case models.BetLegStatusPartial:
+			updates["dead_heat_factor"] = r.factor
+			updates["refund_fraction"] = r.refund
+		}
+		if err := tx.Model(&leg).Updates(updates).Error; err != nil {
+			return err
+		}
+		betIDs[leg.BetID] = true
+	}
+
+	for betID := range betIDs {
+		if err := s.resolveAccumulator(tx, betID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAccumulator recalculates an accumulator's odds without its void legs
+// and settles it once every leg is known
+func (s *Service) resolveAccumulator(tx *gorm.DB, betID uuid.UUID) error {
+	var bet models.Bet
+	if err := tx.Preload("Legs").First(&bet, "id = ? AND status = ?", betID, models.BetStatusPending).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	odds := 1.0
+	known, lost, active := true, false, 0
+	for _, leg := range bet.Legs {
+		switch leg.Status {
+		case models.BetLegStatusPending:
+			known = false
+			odds *= leg.Odds
+			active++
+		case models.BetLegStatusWon:
+			odds *= leg.Odds * leg.DeadHeatFactor
+			active++
+		case models.BetLegStatusPartial:
+			// the leg's return per unit carried forward, the rest of the
+			// stake is lost
+			odds *= leg.Odds*leg.DeadHeatFactor + leg.RefundFraction
+			active++
+		case models.BetLegStatusLost:
+			lost = true
+		}
+	}
+
+	if !known {
+		return tx.Model(&bet).Update("odds", odds).Error
+	}
+
+	now := time.Now()
+
+	switch {
+	case lost:
+		return tx.Model(&bet).Updates(map[string]interface{}{
+			"status":     models.BetStatusLost,
+			"odds":       odds,
+			"settled_at": now,
+		}).Error
+
+	case active == 0:
+		// Every leg was voided, refund the stake
+		if err := tx.Model(&bet).Updates(map[string]interface{}{
+			"status":     models.BetStatusRefunded,
+			"odds":       odds,
+			"settled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return s.credit(tx, bet.UserID, bet.Amount, models.TransactionTypeRefund, bet.ID, "Accumulator void")
+
+	default:
+		payout := bet.Amount * odds
+		if err := tx.Model(&bet).Updates(map[string]interface{}{
+			"status":     models.BetStatusWon,
+			"odds":       odds,
+			"payout":     payout,
+			"settled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return s.credit(tx, bet.UserID, payout, models.TransactionTypeWin, bet.ID, "Accumulator win")
+	}
+}
+
+// credit adds funds to a user's balance and records the transaction against a bet
+func (s *Service) credit(tx *gorm.DB, userID uuid.UUID, amount float64, txType models.TransactionType, betID uuid.UUID, description string) error {
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&user).Update("balance", user.Balance+amount).Error; err != nil {
+		return err
+	}
+
+	transaction := models.Transaction{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Type:          txType,
+		Status:        models.TransactionStatusCompleted,
+		Amount:        amount,
+		ReferenceID:   &betID,
+		ReferenceType: strPtr("bet"),
+		Description:   description,
+	}
+	return tx.Create(&transaction).Error
+}
@@ -0,0 +1,122 @@
+package event
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuoteCashOut values a pending event bet at the outcome's current odds, less the house margin
+func (s *Service) QuoteCashOut(userID, betID uuid.UUID) (*CashOutQuote, error) {
+	bet, err := s.getCashOutBet(s.db, userID, betID)
+	if err != nil {
+		return nil, err
+	}
+	return s.quote(s.db, bet)
+}
+
+// CashOut settles a pending event bet early. The amount must match a fresh
+// quote, so a change in odds since quoting rejects the request.
+func (s *Service) CashOut(userID, betID uuid.UUID, req *CashOutRequest) (*CashOutQuote, error) {
+	var quote *CashOutQuote
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		bet, err := s.getCashOutBet(tx, userID, betID)
+		if err != nil {
+			return err
+		}
+
+		quote, err = s.quote(tx, bet)
+		if err != nil {
+			return err
+		}
+		if quote.Amount != req.Amount {
+			return ErrCashOutQuoteChanged
+		}
+
+		// Guard against the bet being settled or cashed out concurrently
+		result := tx.Model(&models.Bet{}).
+			Where("id = ? AND status = ?", bet.ID, models.BetStatusPending).
+			Updates(map[string]interface{}{
+				"status":     models.BetStatusCashedOut,
+				"payout":     quote.Amount,
+				"settled_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCashOutUnavailable
+		}
+
+		return s.credit(tx, bet.UserID, quote.Amount, models.TransactionTypeCashOut, bet.ID, "Event cash out")
+	})
+
+	if err != nil {
+		return quote, err
+	}
+
+	return quote, nil
+}
+
+// getCashOutBet loads a user's bet and checks it can be cashed out
+func (s *Service) getCashOutBet(db *gorm.DB, userID, betID uuid.UUID) (*models.Bet, error) {
+	var bet models.Bet
+	if err := db.First(&bet, "id = ? AND user_id = ?", betID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBetNotFound
+		}
+		return nil, err
+	}
+
+	if bet.Type != models.BetTypeEvent || bet.Status != models.BetStatusPending || bet.OutcomeID == nil {
+		return nil, ErrCashOutUnavailable
+	}
+
+	var event models.Event
+	if err := db.First(&event, "id = ?", bet.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCashOutUnavailable
+		}
+		return nil, err
+	}
+	if !s.isBettable(&event) || event.Mode == models.EventModePool {
+		return nil, ErrCashOutUnavailable
+	}
+
+	return &bet, nil
+}
+
+// quote prices a bet from its outcome's current odds
+func (s *Service) quote(db *gorm.DB, bet *models.Bet) (*CashOutQuote, error) {
+	var outcome models.EventOutcome
+	if err := db.First(&outcome, "id = ?", bet.OutcomeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCashOutUnavailable
+		}
+		return nil, err
+	}
+
+	if outcome.IsSuspended || outcome.Odds <= 1 {
+		return nil, ErrCashOutUnavailable
+	}
+	if err := s.checkMarketOpen(db, outcome.MarketID); err != nil {
+		return nil, ErrCashOutUnavailable
+	}
+
+	// Fair value is the potential payout discounted by the current implied probability
+	value := bet.Amount * bet.Odds / outcome.Odds * (1 - s.config.CashOutMargin)
+
+	return &CashOutQuote{
+		BetID:       bet.ID,
+		Stake:       bet.Amount,
+		PlacedOdds:  bet.Odds,
+		CurrentOdds: outcome.Odds,
+		Amount:      math.Round(value*100) / 100,
+	}, nil
+}
@@ -1,6 +1,8 @@
 package event
 
 import (
+	"errors"
+	"gamba/auth"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,19 +20,42 @@ func NewController(service *Service) *Controller {
 func (c *Controller) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/events", c.GetAll)
 	r.GET("/events/:id", c.GetByID)
+	r.GET("/events/:id/outcomes/:outcomeId/odds", c.GetOddsHistory)
 
 	r.POST("/events/:id/bet", c.PlaceBet)
+	r.POST("/events/accumulator", c.PlaceAccumulator)
+	r.GET("/events/bets/:betId/cashout", c.QuoteCashOut)
+	r.POST("/events/bets/:betId/cashout", c.CashOut)
+	r.POST("/events/bets/:betId/cancel", c.CancelBet)
+
+	r.GET("/events/favourites", c.GetFavourites)
+	r.POST("/events/:id/favourite", c.AddFavourite)
+	r.DELETE("/events/:id/favourite", c.RemoveFavourite)
+	r.GET("/events/subscriptions", c.GetSubscriptions)
+	r.POST("/events/:id/subscribe", c.Subscribe)
+	r.DELETE("/events/:id/subscribe", c.Unsubscribe)
 }
 
 func (c *Controller) RegisterAdminRoutes(r *gin.RouterGroup) {
 	r.POST("/events", c.Create)
 	r.PUT("/events/:id", c.Update)
 	r.DELETE("/events/:id", c.Delete)
+	r.POST("/events/:id/markets", c.CreateMarket)
+	r.PUT("/events/:id/markets/:marketId", c.UpdateMarket)
+	r.DELETE("/events/:id/markets/:marketId", c.DeleteMarket)
+	r.POST("/events/:id/markets/:marketId/settle", c.SettleMarket)
 	r.POST("/events/:id/outcomes", c.AddOutcome)
 	r.PUT("/events/:id/outcomes/:outcomeId", c.UpdateOutcome)
 	r.DELETE("/events/:id/outcomes/:outcomeId", c.DeleteOutcome)
 	r.POST("/events/:id/settle", c.Settle)
 	r.POST("/events/:id/cancel", c.Cancel)
+	r.POST("/events/:id/rollback", c.Rollback)
+	r.POST("/events/:id/resettle", c.Resettle)
+	r.GET("/events/:id/audit", c.GetSettlementAudit)
+	r.GET("/admin/events/overdue", c.GetOverdue)
+	r.GET("/events/:id/liability", c.GetLiability)
+	r.POST("/events/import", c.ImportEvents)
+	r.POST("/events/import/results", c.ImportResults)
 }
 
 func (c *Controller) GetAll(ctx *gin.Context) {
@@ -117,6 +142,100 @@ func (c *Controller) Delete(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
+func (c *Controller) CreateMarket(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req CreateMarketRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	market, err := c.service.CreateMarket(eventID, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, market)
+}
+
+func (c *Controller) UpdateMarket(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	marketID, err := uuid.Parse(ctx.Param("marketId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid market id"})
+		return
+	}
+
+	var req UpdateMarketRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	market, err := c.service.UpdateMarket(eventID, marketID, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, market)
+}
+
+func (c *Controller) DeleteMarket(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	marketID, err := uuid.Parse(ctx.Param("marketId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid market id"})
+		return
+	}
+
+	if err := c.service.DeleteMarket(eventID, marketID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+func (c *Controller) SettleMarket(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	marketID, err := uuid.Parse(ctx.Param("marketId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid market id"})
+		return
+	}
+
+	var req SettleMarketRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := c.service.SettleMarket(eventID, marketID, &req, getUserID(ctx)); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "market settled"})
+}
+
 func (c *Controller) AddOutcome(ctx *gin.Context) {
 	eventID, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
@@ -193,6 +312,48 @@ func (c *Controller) PlaceBet(ctx *gin.Context) {
 	}
 
 	bet, err := c.service.PlaceBet(userID, eventID, &req)
+	if err == ErrOddsChanged {
+		// Re-quote with the current odds so the client can resubmit
+		outcome, _ := c.service.GetOutcome(req.OutcomeID)
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "outcome": outcome})
+		return
+	}
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, bet)
+}
+
+func (c *Controller) GetOddsHistory(ctx *gin.Context) {
+	outcomeID, err := uuid.Parse(ctx.Param("outcomeId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid outcome id"})
+		return
+	}
+
+	history, err := c.service.GetOddsHistory(outcomeID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, history)
+}
+
+func (c *Controller) PlaceAccumulator(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req PlaceAccumulatorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bet, err := c.service.PlaceAccumulator(userID, &req)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -200,6 +361,190 @@ func (c *Controller) PlaceBet(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, bet)
 }
 
+func (c *Controller) QuoteCashOut(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	betID, err := uuid.Parse(ctx.Param("betId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid bet id"})
+		return
+	}
+
+	quote, err := c.service.QuoteCashOut(userID, betID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, quote)
+}
+
+func (c *Controller) CancelBet(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	betID, err := uuid.Parse(ctx.Param("betId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid bet id"})
+		return
+	}
+
+	bet, err := c.service.CancelBet(userID, betID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, bet)
+}
+
+func (c *Controller) AddFavourite(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	if err := c.service.AddFavourite(userID, eventID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "added to favourites"})
+}
+
+func (c *Controller) RemoveFavourite(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	if err := c.service.RemoveFavourite(userID, eventID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "removed from favourites"})
+}
+
+func (c *Controller) Subscribe(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	if err := c.service.Subscribe(userID, eventID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "subscribed"})
+}
+
+func (c *Controller) Unsubscribe(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	if err := c.service.Unsubscribe(userID, eventID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "unsubscribed"})
+}
+
+func (c *Controller) GetFavourites(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	events, err := c.service.GetFavourites(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+func (c *Controller) GetSubscriptions(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	events, err := c.service.GetSubscriptions(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+func (c *Controller) CashOut(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == uuid.Nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	betID, err := uuid.Parse(ctx.Param("betId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid bet id"})
+		return
+	}
+
+	var req CashOutRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	quote, err := c.service.CashOut(userID, betID, &req)
+	if err == ErrCashOutQuoteChanged {
+		// Return the fresh quote so the client can re-confirm
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "quote": quote})
+		return
+	}
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, quote)
+}
+
 func (c *Controller) Settle(ctx *gin.Context) {
 	eventID, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
@@ -213,7 +558,7 @@ func (c *Controller) Settle(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.service.Settle(eventID, &req); err != nil {
+	if err := c.service.Settle(eventID, getUserID(ctx), &req); err != nil {
 		handleError(ctx, err)
 		return
 	}
@@ -234,13 +579,142 @@ func (c *Controller) Cancel(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "event cancelled"})
 }
 
+func (c *Controller) GetOverdue(ctx *gin.Context) {
+	events, err := c.service.GetOverdue()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+func (c *Controller) Rollback(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req RollbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := c.service.Rollback(eventID, getUserID(ctx), &req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "settlement rolled back"})
+}
+
+func (c *Controller) Resettle(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req ResettleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := c.service.Resettle(eventID, getUserID(ctx), &req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "event resettled"})
+}
+
+func (c *Controller) GetSettlementAudit(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	audits, err := c.service.GetSettlementAudit(eventID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, audits)
+}
+
+func (c *Controller) GetLiability(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	liability, err := c.service.GetLiability(eventID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, liability)
+}
+
+// ImportEvents takes a JSON or CSV (Content-Type: text/csv) event feed; ?dry_run=true only reports changes
+func (c *Controller) ImportEvents(ctx *gin.Context) {
+	feed, err := ParseEventFeed(ctx.Request.Body, feedFormat(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := c.service.ImportEvents(feed, ctx.Query("dry_run") == "true")
+	if err != nil {
+		handleImportError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// ImportResults takes a JSON or CSV results feed; ?dry_run=true only reports changes
+func (c *Controller) ImportResults(ctx *gin.Context) {
+	feed, err := ParseResultsFeed(ctx.Request.Body, feedFormat(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := c.service.ImportResults(feed, getUserID(ctx), ctx.Query("dry_run") == "true")
+	if err != nil {
+		handleImportError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+func feedFormat(ctx *gin.Context) string {
+	if ctx.ContentType() == "text/csv" {
+		return FormatCSV
+	}
+	return FormatJSON
+}
+
+// handleImportError reports feed problems with their detail, which handleError would hide
+func handleImportError(ctx *gin.Context, err error) {
+	if errors.Is(err, ErrInvalidFeed) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if inner := errors.Unwrap(err); inner != nil {
+		err = inner
+	}
+	handleError(ctx, err)
+}
+
 func getUserID(ctx *gin.Context) uuid.UUID {
-	userID, exists := ctx.Get("user_id")
-	if !exists {
+	claims := auth.GetClaims(ctx)
+	if claims == nil {
 		return uuid.Nil
 	}
-	uid, _ := userID.(uuid.UUID)
-	return uid
+	return claims.UserID
 }
 
 func handleError(ctx *gin.Context, err error) {
@@ -251,12 +725,24 @@ func handleError(ctx *gin.Context, err error) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 	case ErrEventNotBettable:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	case ErrEventAlreadySettled:
+	case ErrEventAlreadySettled, ErrEventNotSettled, ErrInvalidResult:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case ErrInsufficientFunds:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case ErrInvalidAmount:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrTooFewLegs, ErrTooManyLegs, ErrDuplicateLegEvent, ErrPoolBetUnsupported:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrBetNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrCashOutUnavailable, ErrCancelUnavailable:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrOddsChanged, ErrMarketHasBets, ErrOutcomeHasBets:
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case ErrMarketNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrMarketSuspended, ErrMarketSettled, ErrMarketUnresolved, ErrInvalidMarket, ErrMarketFull, ErrOutcomeSuspended:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	default:
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 	}
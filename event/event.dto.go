@@ -8,45 +8,242 @@ import (
 )
 
 type CreateRequest struct {
-	Name        string               `json:"name" binding:"required"`
-	Description string               `json:"description"`
-	Category    models.EventCategory `json:"category" binding:"required"`
-	StartsAt    time.Time            `json:"starts_at" binding:"required"`
-	EndsAt      *time.Time           `json:"ends_at,omitempty"`
+	Name           string               `json:"name" binding:"required"`
+	Description    string               `json:"description"`
+	Category       models.EventCategory `json:"category" binding:"required"`
+	StartsAt       time.Time            `json:"starts_at" binding:"required"`
+	EndsAt         *time.Time           `json:"ends_at,omitempty"`
+	NoInPlay       bool                 `json:"no_in_play"`
+	Mode           models.EventMode     `json:"mode" binding:"omitempty,oneof=fixed pool"`
+	PoolCommission *float64             `json:"pool_commission,omitempty" binding:"omitempty,gte=0,lt=1"`
 }
 
 type UpdateRequest struct {
-	Name        *string               `json:"name,omitempty"`
-	Description *string               `json:"description,omitempty"`
-	Category    *models.EventCategory `json:"category,omitempty"`
-	Status      *string               `json:"status,omitempty"`
-	StartsAt    *time.Time            `json:"starts_at,omitempty"`
-	EndsAt      *time.Time            `json:"ends_at,omitempty"`
+	Name           *string               `json:"name,omitempty"`
+	Description    *string               `json:"description,omitempty"`
+	Category       *models.EventCategory `json:"category,omitempty"`
+	Status         *string               `json:"status,omitempty"`
+	StartsAt       *time.Time            `json:"starts_at,omitempty"`
+	EndsAt         *time.Time            `json:"ends_at,omitempty"`
+	NoInPlay       *bool                 `json:"no_in_play,omitempty"`
+	PoolCommission *float64              `json:"pool_commission,omitempty" binding:"omitempty,gte=0,lt=1"`
+}
+
+type CreateMarketRequest struct {
+	Name string            `json:"name" binding:"required"`
+	Type models.MarketType `json:"type" binding:"required"`
+	Line *float64          `json:"line,omitempty"`
+}
+
+type UpdateMarketRequest struct {
+	Name   *string              `json:"name,omitempty"`
+	Line   *float64             `json:"line,omitempty"`
+	Status *models.MarketStatus `json:"status,omitempty"` // open or suspended
 }
 
 type CreateOutcomeRequest struct {
-	Name string  `json:"name" binding:"required"`
-	Odds float64 `json:"odds" binding:"required,gt=1"`
+	Name     string     `json:"name" binding:"required"`
+	Odds     float64    `json:"odds" binding:"required,gt=1"`
+	MarketID *uuid.UUID `json:"market_id,omitempty"` // defaults to the event's winner market
 }
 
 type UpdateOutcomeRequest struct {
-	Name     *string  `json:"name,omitempty"`
-	Odds     *float64 `json:"odds,omitempty"`
-	IsWinner *bool    `json:"is_winner,omitempty"`
+	Name        *string  `json:"name,omitempty"`
+	Odds        *float64 `json:"odds,omitempty" binding:"omitempty,gt=1"`
+	IsWinner    *bool    `json:"is_winner,omitempty"`
+	IsSuspended *bool    `json:"is_suspended,omitempty"`
+	OddsPinned  *bool    `json:"odds_pinned,omitempty"` // setting odds pins them unless this is false
 }
 
 type PlaceBetRequest struct {
-	OutcomeID uuid.UUID `json:"outcome_id" binding:"required"`
-	Amount    float64   `json:"amount" binding:"required,gt=0"`
+	OutcomeID   uuid.UUID `json:"outcome_id" binding:"required"`
+	Amount      float64   `json:"amount" binding:"required,gt=0"`
+	OddsVersion *int      `json:"odds_version,omitempty"` // rejects the bet if the odds have moved since
+}
+
+type AccumulatorLegRequest struct {
+	EventID     uuid.UUID `json:"event_id" binding:"required"`
+	OutcomeID   uuid.UUID `json:"outcome_id" binding:"required"`
+	OddsVersion *int      `json:"odds_version,omitempty"`
+}
+
+type PlaceAccumulatorRequest struct {
+	Legs   []AccumulatorLegRequest `json:"legs" binding:"required,dive"`
+	Amount float64                 `json:"amount" binding:"required,gt=0"`
+}
+
+type CashOutRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"` // amount from the accepted quote
+}
+
+type CashOutQuote struct {
+	BetID       uuid.UUID `json:"bet_id"`
+	Stake       float64   `json:"stake"`
+	PlacedOdds  float64   `json:"placed_odds"`
+	CurrentOdds float64   `json:"current_odds"`
+	Amount      float64   `json:"amount"`
 }
 
+type MarketResult struct {
+	MarketID uuid.UUID `json:"market_id" binding:"required"`
+	SettleMarketRequest
+}
+
+// SettleRequest needs a result for every market that is not already settled,
+// unless Partial is set, in which case unlisted markets stay open
 type SettleRequest struct {
-	WinningOutcomeID uuid.UUID `json:"winning_outcome_id" binding:"required"`
+	Results []MarketResult `json:"results" binding:"required,dive"`
+	Partial bool           `json:"partial"`
+}
+
+type RollbackRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ResettleRequest struct {
+	Reason  string         `json:"reason" binding:"required"`
+	Results []MarketResult `json:"results" binding:"required,dive"`
+}
+
+// SettleMarketRequest takes either a single winning outcome or a list of
+// winners with dead-heat factors. Void outcomes are refunded, partial outcomes
+// pay part of the stake at odds and refund part, every other outcome on the
+// market loses.
+type SettleMarketRequest struct {
+	WinningOutcomeID *uuid.UUID     `json:"winning_outcome_id,omitempty"`
+	Winners          []WinnerInput  `json:"winners,omitempty" binding:"dive"`
+	VoidOutcomeIDs   []uuid.UUID    `json:"void_outcome_ids,omitempty"`
+	Partials         []PartialInput `json:"partials,omitempty" binding:"dive"`
+}
+
+type WinnerInput struct {
+	OutcomeID      uuid.UUID `json:"outcome_id" binding:"required"`
+	DeadHeatFactor float64   `json:"dead_heat_factor" binding:"omitempty,gt=0,lte=1"` // 0 means a full win
+}
+
+// PartialInput splits an outcome's stakes, e.g. half won and half refunded on
+// an Asian quarter line. Whatever is left of the stake loses.
+type PartialInput struct {
+	OutcomeID      uuid.UUID `json:"outcome_id" binding:"required"`
+	WinFraction    float64   `json:"win_fraction" binding:"gte=0,lte=1"`    // share of the stake paid at odds
+	RefundFraction float64   `json:"refund_fraction" binding:"gte=0,lte=1"` // share of the stake returned
 }
 
 type EventFilter struct {
-	Status   *string `form:"status"`
-	Category *string `form:"category"`
-	Limit    int     `form:"limit,default=20"`
-	Offset   int     `form:"offset,default=0"`
+	Status   *string    `form:"status"`
+	Category *string    `form:"category"`
+	Query    string     `form:"q"`    // full-text search on name and description
+	From     *time.Time `form:"from"` // starts_at range
+	To       *time.Time `form:"to"`
+	Sort     string     `form:"sort" binding:"omitempty,oneof=starts_at -starts_at name -name created_at -created_at relevance"`
+	Limit    int        `form:"limit,default=20"`
+	Offset   int        `form:"offset,default=0"`
+}
+
+// OddsChange is pushed to "event:<id>" subscribers when an outcome's odds move
+type OddsChange struct {
+	EventID   uuid.UUID `json:"event_id"`
+	OutcomeID uuid.UUID `json:"outcome_id"`
+	Odds      float64   `json:"odds"`
+	Version   int       `json:"version"`
+}
+
+// EventStatusChange is pushed to "event:<id>" subscribers when an event changes status
+type EventStatusChange struct {
+	EventID uuid.UUID          `json:"event_id"`
+	Status  models.EventStatus `json:"status"`
+}
+
+// OutcomeSuspended is pushed to "event:<id>" subscribers when an outcome stops taking bets
+type OutcomeSuspended struct {
+	EventID   uuid.UUID `json:"event_id"`
+	OutcomeID uuid.UUID `json:"outcome_id"`
+}
+
+type OutcomeLiability struct {
+	OutcomeID       uuid.UUID `json:"outcome_id"`
+	Name            string    `json:"name"`
+	Odds            float64   `json:"odds"`
+	IsSuspended     bool      `json:"is_suspended"`
+	Stake           float64   `json:"stake"`
+	PotentialPayout float64   `json:"potential_payout"`
+	Liability       float64   `json:"liability"` // house net loss if this outcome wins
+}
+
+type MarketLiability struct {
+	MarketID uuid.UUID          `json:"market_id"`
+	Name     string             `json:"name"`
+	Stake    float64            `json:"stake"`
+	Outcomes []OutcomeLiability `json:"outcomes"`
+}
+
+type EventLiability struct {
+	EventID   uuid.UUID         `json:"event_id"`
+	Threshold float64           `json:"threshold"`
+	Markets   []MarketLiability `json:"markets"`
+}
+
+// ImportFeed is a batch of events keyed by the feed's own ids, see ParseEventFeed
+type ImportFeed struct {
+	Events []ImportEvent `json:"events" binding:"required,dive"`
+}
+
+type ImportEvent struct {
+	ExternalID  string               `json:"external_id" binding:"required,max=100"`
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Category    models.EventCategory `json:"category" binding:"required"`
+	StartsAt    time.Time            `json:"starts_at" binding:"required"`
+	EndsAt      *time.Time           `json:"ends_at,omitempty"`
+	NoInPlay    bool                 `json:"no_in_play"`
+	Markets     []ImportMarket       `json:"markets" binding:"dive"`
+}
+
+type ImportMarket struct {
+	ExternalID string            `json:"external_id" binding:"required,max=100"`
+	Name       string            `json:"name" binding:"required"`
+	Type       models.MarketType `json:"type" binding:"required"`
+	Line       *float64          `json:"line,omitempty"`
+	Outcomes   []ImportOutcome   `json:"outcomes" binding:"dive"`
+}
+
+type ImportOutcome struct {
+	ExternalID string  `json:"external_id" binding:"required,max=100"`
+	Name       string  `json:"name" binding:"required"`
+	Odds       float64 `json:"odds" binding:"required,gt=1"`
+}
+
+// ResultsFeed settles or cancels imported events, see ParseResultsFeed
+type ResultsFeed struct {
+	Results []ImportResult `json:"results" binding:"required,dive"`
+}
+
+type ImportResult struct {
+	EventID string               `json:"event_id" binding:"required"` // external ids throughout
+	Cancel  bool                 `json:"cancel"`
+	Markets []ImportMarketResult `json:"markets" binding:"dive"`
+}
+
+type ImportMarketResult struct {
+	MarketID string         `json:"market_id" binding:"required"`
+	Winners  []ImportWinner `json:"winners" binding:"dive"`
+	Void     []string       `json:"void"`
+}
+
+type ImportWinner struct {
+	OutcomeID      string  `json:"outcome_id" binding:"required"`
+	DeadHeatFactor float64 `json:"dead_heat_factor" binding:"omitempty,gt=0,lte=1"`
+}
+
+type ImportChange struct {
+	Action     string `json:"action"` // create, update, settle, cancel or skip
+	Kind       string `json:"kind"`   // event, market or outcome
+	ExternalID string `json:"external_id"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// ImportReport lists what an import changed, or would change on a dry run
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Changes []ImportChange `json:"changes"`
 }
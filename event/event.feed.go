@@ -0,0 +1,141 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OddsProvider supplies fixtures and prices from an external source. Events,
+// markets and outcomes are matched to ours by external id.
+type OddsProvider interface {
+	Name() string
+	Fetch(ctx context.Context) (*ImportFeed, error)
+}
+
+// RunOddsFeed pulls from the provider on a fixed interval and applies any changes
+func (s *Service) RunOddsFeed(provider OddsProvider, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.syncOdds(provider, interval)
+	for range ticker.C {
+		s.syncOdds(provider, interval)
+	}
+}
+
+func (s *Service) syncOdds(provider OddsProvider, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	feed, err := provider.Fetch(ctx)
+	if err != nil {
+		log.Printf("Error fetching odds from %s: %v", provider.Name(), err)
+		return
+	}
+
+	report, err := s.ImportEvents(feed, false)
+	if err != nil {
+		log.Printf("Error applying odds from %s: %v", provider.Name(), err)
+		return
+	}
+	if len(report.Changes) > 0 {
+		log.Printf("Applied %d changes from %s", len(report.Changes), provider.Name())
+	}
+}
+
+// FileProvider reads fixtures from a JSON or CSV feed file on every fetch
+type FileProvider struct {
+	Path string
+}
+
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{Path: path}
+}
+
+func (p *FileProvider) Name() string { return "file " + p.Path }
+
+func (p *FileProvider) Fetch(ctx context.Context) (*ImportFeed, error) {
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	format := FormatJSON
+	if strings.EqualFold(filepath.Ext(p.Path), ".csv") {
+		format = FormatCSV
+	}
+	return ParseEventFeed(f, format)
+}
+
+// HTTPProvider fetches fixtures from a URL serving a JSON or CSV (text/csv) feed
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{URL: url, Client: http.DefaultClient}
+}
+
+func (p *HTTPProvider) Name() string { return p.URL }
+
+func (p *HTTPProvider) Fetch(ctx context.Context) (*ImportFeed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	format := FormatJSON
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		format = FormatCSV
+	}
+	return ParseEventFeed(resp.Body, format)
+}
+
+// MockFeedHandler serves a feed file as JSON for HTTPProvider to poll offline.
+// Each response nudges every price by up to drift (e.g. 0.05 for 5%) to
+// simulate a live market.
+func MockFeedHandler(path string, drift float64) http.Handler {
+	provider := NewFileProvider(path)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed, err := provider.Fetch(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for i := range feed.Events {
+			for j := range feed.Events[i].Markets {
+				outcomes := feed.Events[i].Markets[j].Outcomes
+				for k := range outcomes {
+					odds := outcomes[k].Odds * (1 + (rand.Float64()*2-1)*drift)
+					outcomes[k].Odds = math.Max(minOdds, math.Round(odds*100)/100)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feed)
+	})
+}
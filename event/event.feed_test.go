@@ -0,0 +1,196 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gamba/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const feedJSON = `{"events": [{
+	"external_id": "ev-1", "name": "Home v Away", "category": "sports", "starts_at": "2030-01-01T15:00:00Z",
+	"markets": [{"external_id": "mk-1", "name": "Winner", "type": "winner", "outcomes": [
+		{"external_id": "oc-home", "name": "Home", "odds": 1.8},
+		{"external_id": "oc-away", "name": "Away", "odds": 2.2}
+	]}]
+}]}`
+
+const feedCSV = `event_id,event_name,category,starts_at,market_id,market_name,market_type,outcome_id,outcome_name,odds
+ev-1,Home v Away,sports,2030-01-01T15:00:00Z,mk-1,Winner,winner,oc-home,Home,1.8
+ev-1,Home v Away,sports,2030-01-01T15:00:00Z,mk-1,Winner,winner,oc-away,Away,2.2
+`
+
+func TestFileProviderFetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		outcomes int
+		wantErr  error
+	}{
+		{name: "json", file: "feed.json", content: feedJSON, outcomes: 2},
+		{name: "csv", file: "feed.csv", content: feedCSV, outcomes: 2},
+		{name: "uppercase csv extension", file: "feed.CSV", content: feedCSV, outcomes: 2},
+		{name: "malformed json", file: "feed.json", content: `{"events": [`, wantErr: ErrInvalidFeed},
+		{name: "csv read as json", file: "feed.txt", content: feedCSV, wantErr: ErrInvalidFeed},
+		{name: "odds below evens", file: "feed.json", content: `{"events": [{"external_id": "ev-1", "name": "x", "category": "sports", "starts_at": "2030-01-01T15:00:00Z",
+			"markets": [{"external_id": "mk-1", "name": "Winner", "type": "winner", "outcomes": [{"external_id": "oc-1", "name": "x", "odds": 0.5}]}]}]}`, wantErr: ErrInvalidFeed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			feed, err := NewFileProvider(path).Fetch(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(feed.Events) != 1 || len(feed.Events[0].Markets) != 1 {
+				t.Fatalf("got %d events, want 1 with 1 market", len(feed.Events))
+			}
+			if n := len(feed.Events[0].Markets[0].Outcomes); n != tt.outcomes {
+				t.Fatalf("got %d outcomes, want %d", n, tt.outcomes)
+			}
+		})
+	}
+}
+
+func TestFileProviderMissingFile(t *testing.T) {
+	_, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.json")).Fetch(context.Background())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("err = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+// fakeProvider serves a fixed feed, or fails with err
+type fakeProvider struct {
+	feed *ImportFeed
+	err  error
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Fetch(ctx context.Context) (*ImportFeed, error) {
+	return p.feed, p.err
+}
+
+// priced returns the test feed with the home outcome at odds
+func priced(odds float64) *ImportFeed {
+	return &ImportFeed{Events: []ImportEvent{{
+		ExternalID: "ev-1",
+		Name:       "Home v Away",
+		Category:   models.EventCategory("sports"),
+		StartsAt:   time.Date(2030, 1, 1, 15, 0, 0, 0, time.UTC),
+		Markets: []ImportMarket{{
+			ExternalID: "mk-1",
+			Name:       "Winner",
+			Type:       models.MarketTypeWinner,
+			Outcomes: []ImportOutcome{
+				{ExternalID: "oc-home", Name: "Home", Odds: odds},
+				{ExternalID: "oc-away", Name: "Away", Odds: 2.2},
+			},
+		}},
+	}}}
+}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a new, empty database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	tables := []interface{}{&models.Event{}, &models.EventMarket{}, &models.EventOutcome{}, &models.OddsHistory{}}
+	for _, table := range tables {
+		// sqlite has no gen_random_uuid, ids are always set on create anyway
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(table); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DefaultValue == "gen_random_uuid()" {
+				field.DefaultValue = ""
+				field.HasDefaultValue = false
+			}
+		}
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db, nil, DefaultConfig())
+}
+
+func TestSyncOdds(t *testing.T) {
+	tests := []struct {
+		name     string
+		pinned   bool
+		next     *fakeProvider
+		wantOdds float64
+		wantVer  int
+	}{
+		{name: "reprices", next: &fakeProvider{feed: priced(1.6)}, wantOdds: 1.6, wantVer: 2},
+		{name: "unchanged price", next: &fakeProvider{feed: priced(1.8)}, wantOdds: 1.8, wantVer: 1},
+		{name: "pinned odds kept", pinned: true, next: &fakeProvider{feed: priced(1.6)}, wantOdds: 1.8, wantVer: 1},
+		{name: "fetch error", next: &fakeProvider{err: errors.New("feed down")}, wantOdds: 1.8, wantVer: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+
+			s.syncOdds(&fakeProvider{feed: priced(1.8)}, time.Second)
+
+			var outcome models.EventOutcome
+			if err := s.db.First(&outcome, "external_id = ?", "oc-home").Error; err != nil {
+				t.Fatalf("first sync didn't create the outcome: %v", err)
+			}
+			if tt.pinned {
+				if err := s.db.Model(&outcome).Update("odds_pinned", true).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s.syncOdds(tt.next, time.Second)
+
+			if err := s.db.First(&outcome, "id = ?", outcome.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if outcome.Odds != tt.wantOdds || outcome.OddsVersion != tt.wantVer {
+				t.Fatalf("odds = %v v%d, want %v v%d", outcome.Odds, outcome.OddsVersion, tt.wantOdds, tt.wantVer)
+			}
+
+			var history int64
+			if err := s.db.Model(&models.OddsHistory{}).Where("outcome_id = ?", outcome.ID).Count(&history).Error; err != nil {
+				t.Fatal(err)
+			}
+			if history != int64(tt.wantVer) {
+				t.Fatalf("got %d odds history rows, want %d", history, tt.wantVer)
+			}
+		})
+	}
+}
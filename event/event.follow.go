@@ -0,0 +1,130 @@
+package event
+
+import (
+	"errors"
+	"log"
+
+	"gamba/chat"
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddFavourite stars an event for a user
+func (s *Service) AddFavourite(userID, eventID uuid.UUID) error {
+	if err := s.checkEventExists(eventID); err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.EventFavourite{UserID: userID, EventID: eventID}).Error
+}
+
+// RemoveFavourite unstars an event for a user
+func (s *Service) RemoveFavourite(userID, eventID uuid.UUID) error {
+	return s.db.Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.EventFavourite{}).Error
+}
+
+// GetFavourites returns the events a user has starred
+func (s *Service) GetFavourites(userID uuid.UUID) ([]models.Event, error) {
+	var events []models.Event
+	if err := s.db.Preload("Markets.Outcomes").
+		Joins("JOIN event_favourites ON event_favourites.event_id = events.id").
+		Where("event_favourites.user_id = ?", userID).
+		Order("events.starts_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Subscribe sends a user notifications about an event's odds, start and result
+func (s *Service) Subscribe(userID, eventID uuid.UUID) error {
+	if err := s.checkEventExists(eventID); err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.EventSubscription{UserID: userID, EventID: eventID}).Error
+}
+
+// Unsubscribe stops notifications about an event for a user
+func (s *Service) Unsubscribe(userID, eventID uuid.UUID) error {
+	return s.db.Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.EventSubscription{}).Error
+}
+
+// GetSubscriptions returns the events a user is subscribed to
+func (s *Service) GetSubscriptions(userID uuid.UUID) ([]models.Event, error) {
+	var events []models.Event
+	if err := s.db.Preload("Markets.Outcomes").
+		Joins("JOIN event_subscriptions ON event_subscriptions.event_id = events.id").
+		Where("event_subscriptions.user_id = ?", userID).
+		Order("events.starts_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *Service) checkEventExists(eventID uuid.UUID) error {
+	if err := s.db.Select("id").First(&models.Event{}, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEventNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// eventMessage is a WebSocket message about one event
+type eventMessage struct {
+	eventID uuid.UUID
+	msg     chat.WSMessage
+}
+
+// broadcast sends a message to everyone viewing an event over its topic and
+// to every user subscribed to it
+func (s *Service) broadcast(eventID uuid.UUID, msg chat.WSMessage) {
+	s.broadcastAll([]eventMessage{{eventID: eventID, msg: msg}})
+}
+
+// broadcastAll publishes each message on its event's topic, then sends it to
+// subscribers who aren't already on that topic. Subscribers of every event
+// are loaded in one query.
+func (s *Service) broadcastAll(messages []eventMessage) {
+	if s.hub == nil || len(messages) == 0 {
+		return
+	}
+
+	eventIDs := make([]uuid.UUID, 0, len(messages))
+	seen := make(map[uuid.UUID]bool)
+	for _, m := range messages {
+		s.hub.Publish(eventTopic(m.eventID), m.msg)
+		if !seen[m.eventID] {
+			seen[m.eventID] = true
+			eventIDs = append(eventIDs, m.eventID)
+		}
+	}
+
+	var subscriptions []models.EventSubscription
+	if err := s.db.Select("event_id", "user_id").Where("event_id IN ?", eventIDs).Find(&subscriptions).Error; err != nil {
+		log.Printf("Error loading event subscribers: %v", err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+	subscribers := make(map[uuid.UUID][]uuid.UUID)
+	for _, sub := range subscriptions {
+		subscribers[sub.EventID] = append(subscribers[sub.EventID], sub.UserID)
+	}
+
+	for _, m := range messages {
+		viewing := s.hub.TopicUsers(eventTopic(m.eventID))
+		for _, userID := range subscribers[m.eventID] {
+			if !viewing[userID] {
+				s.hub.SendToUser(userID, m.msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,580 @@
+package event
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gamba/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+var (
+	ErrInvalidFeed = errors.New("invalid feed")
+
+	// errDryRun rolls back an import once its report is built
+	errDryRun = errors.New("dry run")
+)
+
+// ParseEventFeed reads an event feed. CSV feeds have a header row and one row
+// per outcome with the columns event_id, event_name, description, category,
+// starts_at, ends_at, no_in_play, market_id, market_name, market_type, line,
+// outcome_id, outcome_name and odds; event and market columns repeat on each
+// of their outcomes' rows.
+func ParseEventFeed(r io.Reader, format string) (*ImportFeed, error) {
+	var feed ImportFeed
+
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&feed); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+	case FormatCSV:
+		rows, err := readCSV(r)
+		if err != nil {
+			return nil, err
+		}
+
+		events := make(map[string]*ImportEvent)
+		markets := make(map[string]int) // index into its event's Markets
+		var order []string
+		for i, row := range rows {
+			line := i + 2
+
+			eventID := row["event_id"]
+			event, ok := events[eventID]
+			if !ok {
+				startsAt, err := time.Parse(time.RFC3339, row["starts_at"])
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: starts_at: %v", ErrInvalidFeed, line, err)
+				}
+				event = &ImportEvent{
+					ExternalID:  eventID,
+					Name:        row["event_name"],
+					Description: row["description"],
+					Category:    models.EventCategory(row["category"]),
+					StartsAt:    startsAt,
+					NoInPlay:    row["no_in_play"] == "true",
+				}
+				if v := row["ends_at"]; v != "" {
+					endsAt, err := time.Parse(time.RFC3339, v)
+					if err != nil {
+						return nil, fmt.Errorf("%w: line %d: ends_at: %v", ErrInvalidFeed, line, err)
+					}
+					event.EndsAt = &endsAt
+				}
+				events[eventID] = event
+				order = append(order, eventID)
+			}
+
+			marketID := row["market_id"]
+			if marketID == "" {
+				continue
+			}
+			idx, ok := markets[eventID+"/"+marketID]
+			if !ok {
+				market := ImportMarket{
+					ExternalID: marketID,
+					Name:       row["market_name"],
+					Type:       models.MarketType(row["market_type"]),
+				}
+				if v := row["line"]; v != "" {
+					l, err := strconv.ParseFloat(v, 64)
+					if err != nil {
+						return nil, fmt.Errorf("%w: line %d: line: %v", ErrInvalidFeed, line, err)
+					}
+					market.Line = &l
+				}
+				event.Markets = append(event.Markets, market)
+				idx = len(event.Markets) - 1
+				markets[eventID+"/"+marketID] = idx
+			}
+			market := &event.Markets[idx]
+
+			if row["outcome_id"] == "" {
+				continue
+			}
+			odds, err := strconv.ParseFloat(row["odds"], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: odds: %v", ErrInvalidFeed, line, err)
+			}
+			market.Outcomes = append(market.Outcomes, ImportOutcome{
+				ExternalID: row["outcome_id"],
+				Name:       row["outcome_name"],
+				Odds:       odds,
+			})
+		}
+
+		for _, id := range order {
+			feed.Events = append(feed.Events, *events[id])
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidFeed, format)
+	}
+
+	if err := binding.Validator.ValidateStruct(&feed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	return &feed, nil
+}
+
+// ParseResultsFeed reads a results feed. CSV feeds have a header row and the
+// columns event_id, market_id, outcome_id, result and dead_heat_factor, where
+// result is won or void, or cancelled on a row with only an event_id. Outcomes
+// missing from a settled market lose.
+func ParseResultsFeed(r io.Reader, format string) (*ResultsFeed, error) {
+	var feed ResultsFeed
+
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&feed); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+	case FormatCSV:
+		rows, err := readCSV(r)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make(map[string]*ImportResult)
+		var order []string
+		for i, row := range rows {
+			line := i + 2
+
+			eventID := row["event_id"]
+			result, ok := results[eventID]
+			if !ok {
+				result = &ImportResult{EventID: eventID}
+				results[eventID] = result
+				order = append(order, eventID)
+			}
+
+			if row["result"] == "cancelled" {
+				result.Cancel = true
+				continue
+			}
+
+			var market *ImportMarketResult
+			for j := range result.Markets {
+				if result.Markets[j].MarketID == row["market_id"] {
+					market = &result.Markets[j]
+				}
+			}
+			if market == nil {
+				result.Markets = append(result.Markets, ImportMarketResult{MarketID: row["market_id"]})
+				market = &result.Markets[len(result.Markets)-1]
+			}
+
+			switch row["result"] {
+			case "won":
+				winner := ImportWinner{OutcomeID: row["outcome_id"]}
+				if v := row["dead_heat_factor"]; v != "" {
+					if winner.DeadHeatFactor, err = strconv.ParseFloat(v, 64); err != nil {
+						return nil, fmt.Errorf("%w: line %d: dead_heat_factor: %v", ErrInvalidFeed, line, err)
+					}
+				}
+				market.Winners = append(market.Winners, winner)
+			case "void":
+				market.Void = append(market.Void, row["outcome_id"])
+			default:
+				return nil, fmt.Errorf("%w: line %d: unknown result %q", ErrInvalidFeed, line, row["result"])
+			}
+		}
+
+		for _, id := range order {
+			feed.Results = append(feed.Results, *results[id])
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidFeed, format)
+	}
+
+	if err := binding.Validator.ValidateStruct(&feed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	return &feed, nil
+}
+
+// readCSV returns each row of a CSV file keyed by its header
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidFeed)
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(name)] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ImportEvents creates or updates events, markets and outcomes from a feed,
+// matching them on external id. A dry run reports the changes without saving them (admin only).
+func (s *Service) ImportEvents(feed *ImportFeed, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Changes: []ImportChange{}}
+	var repriced []models.EventOutcome
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range feed.Events {
+			if err := s.importEvent(tx, &feed.Events[i], report, &repriced); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !dryRun {
+		messages := make([]eventMessage, len(repriced))
+		for i := range repriced {
+			messages[i] = oddsMessage(&repriced[i])
+		}
+		s.broadcastAll(messages)
+	}
+	return report, nil
+}
+
+func (s *Service) importEvent(tx *gorm.DB, in *ImportEvent, report *ImportReport, repriced *[]models.EventOutcome) error {
+	var event models.Event
+	err := tx.Where("external_id = ?", in.ExternalID).First(&event).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		event = models.Event{
+			ID:          uuid.New(),
+			ExternalID:  &in.ExternalID,
+			Name:        in.Name,
+			Description: in.Description,
+			Category:    in.Category,
+			Status:      models.EventStatusUpcoming,
+			StartsAt:    in.StartsAt,
+			EndsAt:      in.EndsAt,
+			NoInPlay:    in.NoInPlay,
+			Mode:        models.EventModeFixed,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		report.add("create", "event", in.ExternalID, in.Name)
+
+	case err != nil:
+		return err
+
+	case event.Status == models.EventStatusCompleted || event.Status == models.EventStatusCancelled:
+		report.add("skip", "event", in.ExternalID, "event already "+string(event.Status))
+		return nil
+
+	default:
+		updates := make(map[string]interface{})
+		if event.Name != in.Name {
+			updates["name"] = in.Name
+		}
+		if event.Description != in.Description {
+			updates["description"] = in.Description
+		}
+		if event.Category != in.Category {
+			updates["category"] = in.Category
+		}
+		if !event.StartsAt.Equal(in.StartsAt) {
+			updates["starts_at"] = in.StartsAt
+		}
+		if in.EndsAt != nil && (event.EndsAt == nil || !event.EndsAt.Equal(*in.EndsAt)) {
+			updates["ends_at"] = *in.EndsAt
+		}
+		if event.NoInPlay != in.NoInPlay {
+			updates["no_in_play"] = in.NoInPlay
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&event).Updates(updates).Error; err != nil {
+				return err
+			}
+			report.add("update", "event", in.ExternalID, changedFields(updates))
+		}
+	}
+
+	for i := range in.Markets {
+		if err := s.importMarket(tx, &event, &in.Markets[i], report, repriced); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) importMarket(tx *gorm.DB, event *models.Event, in *ImportMarket, report *ImportReport, repriced *[]models.EventOutcome) error {
+	if !validMarket(in.Type, in.Line) {
+		return fmt.Errorf("%w: market %s: %v", ErrInvalidFeed, in.ExternalID, ErrInvalidMarket)
+	}
+
+	var market models.EventMarket
+	err := tx.Where("event_id = ? AND external_id = ?", event.ID, in.ExternalID).First(&market).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		market = models.EventMarket{
+			ID:         uuid.New(),
+			EventID:    event.ID,
+			ExternalID: &in.ExternalID,
+			Name:       in.Name,
+			Type:       in.Type,
+			Line:       in.Line,
+			Status:     models.MarketStatusOpen,
+		}
+		if err := tx.Create(&market).Error; err != nil {
+			return err
+		}
+		report.add("create", "market", in.ExternalID, in.Name)
+
+	case err != nil:
+		return err
+
+	case market.Type != in.Type:
+		return fmt.Errorf("%w: market %s does not match its existing type", ErrInvalidFeed, in.ExternalID)
+
+	case market.Status == models.MarketStatusSettled || market.Status == models.MarketStatusVoid:
+		report.add("skip", "market", in.ExternalID, "market already "+string(market.Status))
+		return nil
+
+	default:
+		updates := make(map[string]interface{})
+		if market.Name != in.Name {
+			updates["name"] = in.Name
+		}
+		if in.Line != nil && (market.Line == nil || *market.Line != *in.Line) {
+			updates["line"] = *in.Line
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&market).Updates(updates).Error; err != nil {
+				return err
+			}
+			report.add("update", "market", in.ExternalID, changedFields(updates))
+		}
+	}
+
+	for i := range in.Outcomes {
+		if err := s.importOutcome(tx, &market, &in.Outcomes[i], report, repriced); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) importOutcome(tx *gorm.DB, market *models.EventMarket, in *ImportOutcome, report *ImportReport, repriced *[]models.EventOutcome) error {
+	var outcome models.EventOutcome
+	err := tx.Where("market_id = ? AND external_id = ?", market.ID, in.ExternalID).First(&outcome).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if _, err := s.outcomeMarket(tx, market.EventID, &market.ID); err != nil {
+			return fmt.Errorf("%w: outcome %s: %v", ErrInvalidFeed, in.ExternalID, err)
+		}
+		outcome = models.EventOutcome{
+			ID:          uuid.New(),
+			EventID:     market.EventID,
+			MarketID:    market.ID,
+			ExternalID:  &in.ExternalID,
+			Name:        in.Name,
+			Odds:        in.Odds,
+			OddsVersion: 1,
+		}
+		if err := tx.Create(&outcome).Error; err != nil {
+			return err
+		}
+		if err := recordOdds(tx, &outcome); err != nil {
+			return err
+		}
+		report.add("create", "outcome", in.ExternalID, fmt.Sprintf("%s @ %.2f", in.Name, in.Odds))
+
+	case err != nil:
+		return err
+
+	default:
+		updates := make(map[string]interface{})
+		if outcome.Name != in.Name {
+			updates["name"] = in.Name
+		}
+		// pinned odds were set by hand or shortened on liability, the feed
+		// doesn't get to undo that
+		oddsChanged := outcome.Odds != in.Odds && !outcome.OddsPinned
+		if oddsChanged {
+			updates["odds"] = in.Odds
+			updates["odds_version"] = gorm.Expr("odds_version + 1")
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&outcome).Updates(updates).Error; err != nil {
+			return err
+		}
+		if oddsChanged {
+			if err := tx.First(&outcome, "id = ?", outcome.ID).Error; err != nil {
+				return err
+			}
+			if err := recordOdds(tx, &outcome); err != nil {
+				return err
+			}
+			*repriced = append(*repriced, outcome)
+		}
+		report.add("update", "outcome", in.ExternalID, changedFields(updates))
+	}
+	return nil
+}
+
+// ImportResults settles or cancels events from a results feed. Markets left
+// out of an event's result stay open. A dry run reports the changes without saving them (admin only).
+func (s *Service) ImportResults(feed *ResultsFeed, adminID uuid.UUID, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Changes: []ImportChange{}}
+	var settled []models.Event
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range feed.Results {
+			if err := s.importResult(tx, &feed.Results[i], adminID, report, &settled); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !dryRun {
+		for _, event := range settled {
+			s.publishStatus(event.ID, event.Status)
+		}
+	}
+	return report, nil
+}
+
+func (s *Service) importResult(tx *gorm.DB, in *ImportResult, adminID uuid.UUID, report *ImportReport, settled *[]models.Event) error {
+	var event models.Event
+	if err := tx.Where("external_id = ?", in.EventID).First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: unknown event %s", ErrInvalidFeed, in.EventID)
+		}
+		return err
+	}
+
+	if event.Status == models.EventStatusCompleted || event.Status == models.EventStatusCancelled {
+		report.add("skip", "event", in.EventID, "event already "+string(event.Status))
+		return nil
+	}
+
+	if in.Cancel {
+		if err := s.cancelEvent(tx, &event); err != nil {
+			return err
+		}
+		event.Status = models.EventStatusCancelled
+		*settled = append(*settled, event)
+		report.add("cancel", "event", in.EventID, event.Name)
+		return nil
+	}
+
+	results := make([]MarketResult, 0, len(in.Markets))
+	for _, m := range in.Markets {
+		var market models.EventMarket
+		if err := tx.Where("external_id = ? AND event_id = ?", m.MarketID, event.ID).First(&market).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: unknown market %s on event %s", ErrInvalidFeed, m.MarketID, in.EventID)
+			}
+			return err
+		}
+		if market.Status == models.MarketStatusSettled || market.Status == models.MarketStatusVoid {
+			report.add("skip", "market", m.MarketID, "market already "+string(market.Status))
+			continue
+		}
+
+		result := MarketResult{MarketID: market.ID}
+		for _, w := range m.Winners {
+			outcomeID, err := importedOutcome(tx, market.ID, w.OutcomeID)
+			if err != nil {
+				return err
+			}
+			result.Winners = append(result.Winners, WinnerInput{OutcomeID: outcomeID, DeadHeatFactor: w.DeadHeatFactor})
+		}
+		for _, v := range m.Void {
+			outcomeID, err := importedOutcome(tx, market.ID, v)
+			if err != nil {
+				return err
+			}
+			result.VoidOutcomeIDs = append(result.VoidOutcomeIDs, outcomeID)
+		}
+		results = append(results, result)
+		report.add("settle", "market", m.MarketID, fmt.Sprintf("%d winner(s), %d void", len(m.Winners), len(m.Void)))
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	if err := s.settleEvent(tx, &event, results, true); err != nil {
+		return fmt.Errorf("event %s: %w", in.EventID, err)
+	}
+	if event.Status == models.EventStatusCompleted {
+		*settled = append(*settled, event)
+	}
+	return s.audit(tx, event.ID, adminID, models.SettlementActionSettle, "results import", in)
+}
+
+// importedOutcome looks up an outcome on a market by its external id
+func importedOutcome(tx *gorm.DB, marketID uuid.UUID, externalID string) (uuid.UUID, error) {
+	var outcome models.EventOutcome
+	if err := tx.Where("external_id = ? AND market_id = ?", externalID, marketID).First(&outcome).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, fmt.Errorf("%w: unknown outcome %s", ErrInvalidFeed, externalID)
+		}
+		return uuid.Nil, err
+	}
+	return outcome.ID, nil
+}
+
+func (r *ImportReport) add(action, kind, externalID, detail string) {
+	r.Changes = append(r.Changes, ImportChange{
+		Action:     action,
+		Kind:       kind,
+		ExternalID: externalID,
+		Detail:     detail,
+	})
+}
+
+// changedFields lists the columns in an update map for a report
+func changedFields(updates map[string]interface{}) string {
+	fields := make([]string, 0, len(updates))
+	for k := range updates {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,204 @@
+package event
+
+import (
+	"errors"
+	"math"
+
+	"gamba/chat"
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// minOdds is the shortest price automatic odds adjustment will offer
+const minOdds = 1.01
+
+// liabilityAction reports what enforceLiability changed on an outcome
+type liabilityAction struct {
+	oddsChanged bool
+	suspended   bool
+}
+
+// GetLiability returns stakes, potential payouts and liability for every outcome on an event (admin only)
+func (s *Service) GetLiability(eventID uuid.UUID) (*EventLiability, error) {
+	var event models.Event
+	if err := s.db.Preload("Markets").First(&event, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	result := &EventLiability{
+		EventID:   eventID,
+		Threshold: s.config.LiabilityThreshold,
+		Markets:   make([]MarketLiability, 0, len(event.Markets)),
+	}
+
+	for _, m := range event.Markets {
+		market, err := s.marketLiability(s.db, &m)
+		if err != nil {
+			return nil, err
+		}
+		result.Markets = append(result.Markets, *market)
+	}
+
+	return result, nil
+}
+
+// marketLiability works out what the house pays if each outcome on a market wins.
+// Liability is the potential payout less the single bet stakes taken on the
+// whole market; accumulator payouts count in full against each pending leg.
+func (s *Service) marketLiability(db *gorm.DB, market *models.EventMarket) (*MarketLiability, error) {
+	var outcomes []models.EventOutcome
+	if err := db.Where("market_id = ?", market.ID).Order("created_at ASC").Find(&outcomes).Error; err != nil {
+		return nil, err
+	}
+
+	result := &MarketLiability{
+		MarketID: market.ID,
+		Name:     market.Name,
+		Outcomes: make([]OutcomeLiability, 0, len(outcomes)),
+	}
+	if len(outcomes) == 0 {
+		return result, nil
+	}
+
+	outcomeIDs := make([]uuid.UUID, 0, len(outcomes))
+	for _, o := range outcomes {
+		outcomeIDs = append(outcomeIDs, o.ID)
+	}
+
+	type row struct {
+		OutcomeID uuid.UUID
+		Stake     float64
+		Payout    float64
+	}
+
+	var singles []row
+	if err := db.Model(&models.Bet{}).
+		Select("outcome_id, SUM(amount) AS stake, SUM(amount * odds) AS payout").
+		Where("outcome_id IN ? AND status = ?", outcomeIDs, models.BetStatusPending).
+		Group("outcome_id").
+		Scan(&singles).Error; err != nil {
+		return nil, err
+	}
+
+	var accumulators []row
+	if err := db.Table("bet_legs").
+		Select("bet_legs.outcome_id, SUM(bets.amount * bets.odds) AS payout").
+		Joins("JOIN bets ON bets.id = bet_legs.bet_id").
+		Where("bet_legs.outcome_id IN ? AND bet_legs.status = ? AND bets.status = ?",
+			outcomeIDs, models.BetLegStatusPending, models.BetStatusPending).
+		Group("bet_legs.outcome_id").
+		Scan(&accumulators).Error; err != nil {
+		return nil, err
+	}
+
+	stakes := make(map[uuid.UUID]float64)
+	payouts := make(map[uuid.UUID]float64)
+	for _, r := range singles {
+		stakes[r.OutcomeID] += r.Stake
+		payouts[r.OutcomeID] += r.Payout
+		result.Stake += r.Stake
+	}
+	for _, r := range accumulators {
+		payouts[r.OutcomeID] += r.Payout
+	}
+
+	for _, o := range outcomes {
+		result.Outcomes = append(result.Outcomes, OutcomeLiability{
+			OutcomeID:       o.ID,
+			Name:            o.Name,
+			Odds:            o.Odds,
+			IsSuspended:     o.IsSuspended,
+			Stake:           stakes[o.ID],
+			PotentialPayout: payouts[o.ID],
+			Liability:       payouts[o.ID] - result.Stake,
+		})
+	}
+
+	return result, nil
+}
+
+// enforceLiability suspends an outcome once its liability passes the configured
+// threshold and, if enabled, shortens its odds once past half of it
+func (s *Service) enforceLiability(tx *gorm.DB, outcome *models.EventOutcome) (liabilityAction, error) {
+	var action liabilityAction
+	if s.config.LiabilityThreshold <= 0 {
+		return action, nil
+	}
+
+	market, err := s.marketLiability(tx, &models.EventMarket{ID: outcome.MarketID})
+	if err != nil {
+		return action, err
+	}
+
+	var liability float64
+	for _, o := range market.Outcomes {
+		if o.OutcomeID == outcome.ID {
+			liability = o.Liability
+		}
+	}
+
+	switch {
+	case liability > s.config.LiabilityThreshold:
+		if outcome.IsSuspended {
+			return action, nil
+		}
+		if err := tx.Model(outcome).Update("is_suspended", true).Error; err != nil {
+			return action, err
+		}
+		action.suspended = true
+
+	case s.config.OddsAdjustStep > 0 && liability > s.config.LiabilityThreshold/2:
+		odds := math.Max(minOdds, math.Round(outcome.Odds*(1-s.config.OddsAdjustStep)*100)/100)
+		if odds >= outcome.Odds {
+			return action, nil
+		}
+		if err := tx.Model(outcome).Updates(map[string]interface{}{
+			"odds":         odds,
+			"odds_version": gorm.Expr("odds_version + 1"),
+			"odds_pinned":  true,
+		}).Error; err != nil {
+			return action, err
+		}
+		if err := tx.First(outcome, "id = ?", outcome.ID).Error; err != nil {
+			return action, err
+		}
+		if err := recordOdds(tx, outcome); err != nil {
+			return action, err
+		}
+		action.oddsChanged = true
+	}
+
+	return action, nil
+}
+
+// publishLiability tells event subscribers about changes made by enforceLiability
+func (s *Service) publishLiability(outcome *models.EventOutcome, action liabilityAction) {
+	if action.oddsChanged {
+		s.publishOdds(outcome)
+	}
+	if action.suspended {
+		s.broadcast(outcome.EventID, chat.WSMessage{
+			Type: "outcome_suspended",
+			Payload: OutcomeSuspended{
+				EventID:   outcome.EventID,
+				OutcomeID: outcome.ID,
+			},
+		})
+	}
+}
+
+// recordOdds adds the outcome's current odds to its history
+func recordOdds(tx *gorm.DB, outcome *models.EventOutcome) error {
+	return tx.Create(&models.OddsHistory{
+		ID:        uuid.New(),
+		OutcomeID: outcome.ID,
+		EventID:   outcome.EventID,
+		Version:   outcome.OddsVersion,
+		Odds:      outcome.Odds,
+	}).Error
+}
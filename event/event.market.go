@@ -0,0 +1,445 @@
+package event
+
+import (
+	"errors"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateMarket adds a market to an event (admin only)
+func (s *Service) CreateMarket(eventID uuid.UUID, req *CreateMarketRequest) (*models.EventMarket, error) {
+	var event models.Event
+	if err := s.db.First(&event, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	if !validMarket(req.Type, req.Line) {
+		return nil, ErrInvalidMarket
+	}
+
+	market := models.EventMarket{
+		ID:      uuid.New(),
+		EventID: eventID,
+		Name:    req.Name,
+		Type:    req.Type,
+		Line:    req.Line,
+		Status:  models.MarketStatusOpen,
+	}
+
+	if err := s.db.Create(&market).Error; err != nil {
+		return nil, err
+	}
+	return &market, nil
+}
+
+// UpdateMarket renames, re-lines, suspends or reopens a market (admin only)
+func (s *Service) UpdateMarket(eventID, marketID uuid.UUID, req *UpdateMarketRequest) (*models.EventMarket, error) {
+	market, err := s.getMarket(s.db, eventID, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if market.Status == models.MarketStatusSettled || market.Status == models.MarketStatusVoid {
+		return nil, ErrMarketSettled
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Line != nil {
+		if !validMarket(market.Type, req.Line) {
+			return nil, ErrInvalidMarket
+		}
+		updates["line"] = *req.Line
+	}
+	if req.Status != nil {
+		if *req.Status != models.MarketStatusOpen && *req.Status != models.MarketStatusSuspended {
+			return nil, ErrInvalidMarket
+		}
+		updates["status"] = *req.Status
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(market).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return market, nil
+}
+
+// DeleteMarket deletes a market that has no pending bets (admin only)
+func (s *Service) DeleteMarket(eventID, marketID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var market models.EventMarket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&market, "id = ? AND event_id = ?", marketID, eventID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMarketNotFound
+			}
+			return err
+		}
+
+		// deleting would strand the stakes, the market must be settled or voided first
+		pending, err := hasPendingBets(tx, tx.Model(&models.EventOutcome{}).Select("id").Where("market_id = ?", market.ID))
+		if err != nil {
+			return err
+		}
+		if pending {
+			return ErrMarketHasBets
+		}
+
+		return tx.Delete(&market).Error
+	})
+}
+
+// hasPendingBets reports whether any single bet or accumulator leg on the
+// outcomes, a list of ids or a subquery selecting them, is still unsettled
+func hasPendingBets(tx *gorm.DB, outcomes interface{}) (bool, error) {
+	var pending int64
+	if err := tx.Model(&models.Bet{}).
+		Where("outcome_id IN (?) AND status = ?", outcomes, models.BetStatusPending).
+		Count(&pending).Error; err != nil {
+		return false, err
+	}
+	if pending > 0 {
+		return true, nil
+	}
+	if err := tx.Model(&models.BetLeg{}).
+		Where("outcome_id IN (?) AND status = ?", outcomes, models.BetLegStatusPending).
+		Count(&pending).Error; err != nil {
+		return false, err
+	}
+	return pending > 0, nil
+}
+
+// SettleMarket settles a single market ahead of the rest of the event (admin only)
+func (s *Service) SettleMarket(eventID, marketID uuid.UUID, req *SettleMarketRequest, adminID uuid.UUID) error {
+	var event models.Event
+	if err := s.db.First(&event, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEventNotFound
+		}
+		return err
+	}
+
+	if event.Status == models.EventStatusCompleted || event.Status == models.EventStatusCancelled {
+		return ErrEventAlreadySettled
+	}
+
+	market, err := s.getMarket(s.db, eventID, marketID)
+	if err != nil {
+		return err
+	}
+
+	if market.Status == models.MarketStatusSettled || market.Status == models.MarketStatusVoid {
+		return ErrMarketSettled
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.settleMarket(tx, &event, market, req); err != nil {
+			return err
+		}
+		return s.audit(tx, eventID, adminID, models.SettlementActionSettle, "market "+market.Name, MarketResult{MarketID: marketID, SettleMarketRequest: *req})
+	})
+}
+
+// outcomeResult is how a single outcome settled
+type outcomeResult struct {
+	status models.BetLegStatus
+	factor float64 // dead-heat reduction applied to winning odds, or the won share on a partial
+	refund float64 // share of the stake returned on a partial
+}
+
+// payout is what a stake at odds returns on this result
+func (r outcomeResult) payout(stake, odds float64) float64 {
+	return stake * (odds*r.factor + r.refund)
+}
+
+// marketResults validates a settlement request against a market's outcomes and
+// resolves every outcome to won, lost or void
+func marketResults(req *SettleMarketRequest, outcomes []models.EventOutcome) (map[uuid.UUID]outcomeResult, error) {
+	winners := req.Winners
+	if req.WinningOutcomeID != nil {
+		if len(winners) > 0 {
+			return nil, ErrInvalidResult
+		}
+		winners = []WinnerInput{{OutcomeID: *req.WinningOutcomeID}}
+	}
+	if len(winners) == 0 && len(req.VoidOutcomeIDs) == 0 && len(req.Partials) == 0 {
+		return nil, ErrInvalidResult
+	}
+
+	results := make(map[uuid.UUID]outcomeResult, len(outcomes))
+	for _, o := range outcomes {
+		results[o.ID] = outcomeResult{status: models.BetLegStatusLost}
+	}
+
+	for _, w := range winners {
+		r, ok := results[w.OutcomeID]
+		if !ok {
+			return nil, ErrOutcomeNotFound
+		}
+		if r.status != models.BetLegStatusLost {
+			return nil, ErrInvalidResult
+		}
+		factor := w.DeadHeatFactor
+		if factor == 0 {
+			factor = 1
+		}
+		results[w.OutcomeID] = outcomeResult{status: models.BetLegStatusWon, factor: factor}
+	}
+
+	for _, id := range req.VoidOutcomeIDs {
+		r, ok := results[id]
+		if !ok {
+			return nil, ErrOutcomeNotFound
+		}
+		if r.status != models.BetLegStatusLost {
+			return nil, ErrInvalidResult
+		}
+		results[id] = outcomeResult{status: models.BetLegStatusVoid}
+	}
+
+	for _, p := range req.Partials {
+		r, ok := results[p.OutcomeID]
+		if !ok {
+			return nil, ErrOutcomeNotFound
+		}
+		if r.status != models.BetLegStatusLost {
+			return nil, ErrInvalidResult
+		}
+		if p.WinFraction+p.RefundFraction <= 0 || p.WinFraction+p.RefundFraction > 1 {
+			return nil, ErrInvalidResult
+		}
+		results[p.OutcomeID] = outcomeResult{
+			status: models.BetLegStatusPartial,
+			factor: p.WinFraction,
+			refund: p.RefundFraction,
+		}
+	}
+
+	return results, nil
+}
+
+// settleMarket records the result on each outcome, settles single bets and
+// resolves accumulator legs on the market's outcomes
+func (s *Service) settleMarket(tx *gorm.DB, event *models.Event, market *models.EventMarket, req *SettleMarketRequest) error {
+	var outcomes []models.EventOutcome
+	if err := tx.Where("market_id = ?", market.ID).Find(&outcomes).Error; err != nil {
+		return err
+	}
+
+	results, err := marketResults(req, outcomes)
+	if err != nil {
+		return err
+	}
+	if event.Mode == models.EventModePool && len(req.Partials) > 0 {
+		// pool dividends have no odds to split a stake against
+		return ErrInvalidResult
+	}
+
+	outcomeIDs := make([]uuid.UUID, 0, len(outcomes))
+	for _, o := range outcomes {
+		outcomeIDs = append(outcomeIDs, o.ID)
+
+		r := results[o.ID]
+		updates := map[string]interface{}{
+			"is_winner":        r.status == models.BetLegStatusWon || (r.status == models.BetLegStatusPartial && r.factor > 0),
+			"is_void":          r.status == models.BetLegStatusVoid,
+			"dead_heat_factor": nil,
+			"refund_fraction":  nil,
+		}
+		switch r.status {
+		case models.BetLegStatusWon:
+			updates["dead_heat_factor"] = r.factor
+		case models.BetLegStatusPartial:
+			updates["dead_heat_factor"] = r.factor
+			updates["refund_fraction"] = r.refund
+		}
+		if err := tx.Model(&o).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if event.Mode == models.EventModePool {
+		if err := s.settlePool(tx, event, outcomeIDs, results); err != nil {
+			return err
+		}
+		return tx.Model(market).Update("status", models.MarketStatusSettled).Error
+	}
+
+	// Get all pending single bets on this market
+	var bets []models.Bet
+	if err := tx.Where("outcome_id IN ? AND status = ?", outcomeIDs, models.BetStatusPending).Find(&bets).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, bet := range bets {
+		r := results[*bet.OutcomeID]
+		switch r.status {
+		case models.BetLegStatusWon, models.BetLegStatusPartial:
+			// Winner, reduced by the dead-heat factor on a tie or split on a
+			// partial result. A partial with no winning share only refunds
+			// part of the stake, so it settles as refunded rather than won.
+			payout := r.payout(bet.Amount, bet.Odds)
+			status, txType, desc := models.BetStatusWon, models.TransactionTypeWin, "Event win: "
+			if r.factor == 0 {
+				status, txType, desc = models.BetStatusRefunded, models.TransactionTypeRefund, "Partial refund: "
+			}
+
+			if err := tx.Model(&bet).Updates(map[string]interface{}{
+				"status":     status,
+				"payout":     payout,
+				"settled_at": now,
+			}).Error; err != nil {
+				return err
+			}
+
+			if err := s.credit(tx, bet.UserID, payout, txType, bet.ID, desc+event.Name); err != nil {
+				return err
+			}
+
+		case models.BetLegStatusVoid:
+			// Void outcome, refund the stake
+			if err := tx.Model(&bet).Updates(map[string]interface{}{
+				"status":     models.BetStatusRefunded,
+				"settled_at": now,
+			}).Error; err != nil {
+				return err
+			}
+
+			if err := s.credit(tx, bet.UserID, bet.Amount, models.TransactionTypeRefund, bet.ID, "Outcome void: "+event.Name); err != nil {
+				return err
+			}
+
+		default:
+			// Loser
+			if err := tx.Model(&bet).Updates(map[string]interface{}{
+				"status":     models.BetStatusLost,
+				"settled_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	// Resolve accumulator legs on this market
+	var legs []models.BetLeg
+	if err := tx.Where("outcome_id IN ? AND status = ?", outcomeIDs, models.BetLegStatusPending).Find(&legs).Error; err != nil {
+		return err
+	}
+	if err := s.settleLegs(tx, legs, results); err != nil {
+		return err
+	}
+
+	return tx.Model(market).Update("status", models.MarketStatusSettled).Error
+}
+
+// getMarket loads a market and checks it belongs to the event
+func (s *Service) getMarket(db *gorm.DB, eventID, marketID uuid.UUID) (*models.EventMarket, error) {
+	var market models.EventMarket
+	if err := db.First(&market, "id = ? AND event_id = ?", marketID, eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMarketNotFound
+		}
+		return nil, err
+	}
+	return &market, nil
+}
+
+// outcomeMarket resolves the market a new outcome goes into, falling back to
+// the event's winner market and creating it if needed
+func (s *Service) outcomeMarket(tx *gorm.DB, eventID uuid.UUID, marketID *uuid.UUID) (*models.EventMarket, error) {
+	var market models.EventMarket
+
+	if marketID != nil {
+		m, err := s.getMarket(tx, eventID, *marketID)
+		if err != nil {
+			return nil, err
+		}
+		market = *m
+	} else {
+		err := tx.Where("event_id = ? AND type = ?", eventID, models.MarketTypeWinner).Order("created_at ASC").First(&market).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			market = models.EventMarket{
+				ID:      uuid.New(),
+				EventID: eventID,
+				Name:    "Winner",
+				Type:    models.MarketTypeWinner,
+				Status:  models.MarketStatusOpen,
+			}
+			if err := tx.Create(&market).Error; err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if market.Status == models.MarketStatusSettled || market.Status == models.MarketStatusVoid {
+		return nil, ErrMarketSettled
+	}
+
+	if limit := maxOutcomes(market.Type); limit > 0 {
+		var count int64
+		if err := tx.Model(&models.EventOutcome{}).Where("market_id = ?", market.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count >= int64(limit) {
+			return nil, ErrMarketFull
+		}
+	}
+
+	return &market, nil
+}
+
+// checkMarketOpen returns an error unless the market is taking bets
+func (s *Service) checkMarketOpen(db *gorm.DB, marketID uuid.UUID) error {
+	var market models.EventMarket
+	if err := db.First(&market, "id = ?", marketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMarketNotFound
+		}
+		return err
+	}
+	if market.Status != models.MarketStatusOpen {
+		return ErrMarketSuspended
+	}
+	return nil
+}
+
+// validMarket checks the market type is known and carries a line when it needs one
+func validMarket(marketType models.MarketType, line *float64) bool {
+	switch marketType {
+	case models.MarketTypeWinner, models.MarketType1X2, models.MarketTypeYesNo:
+		return line == nil
+	case models.MarketTypeOverUnder, models.MarketTypeHandicap:
+		return line != nil
+	default:
+		return false
+	}
+}
+
+// maxOutcomes returns how many outcomes a market type can hold, 0 = unlimited
+func maxOutcomes(marketType models.MarketType) int {
+	switch marketType {
+	case models.MarketType1X2:
+		return 3
+	case models.MarketTypeOverUnder, models.MarketTypeHandicap, models.MarketTypeYesNo:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,158 @@
+package event
+
+import (
+	"math"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// poolStakes sums the stakes placed on each outcome of a pool event. Refunded
+// and cancelled bets are out of the pool.
+func (s *Service) poolStakes(db *gorm.DB, outcomeIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	var rows []struct {
+		OutcomeID uuid.UUID
+		Stake     float64
+	}
+	if err := db.Model(&models.Bet{}).
+		Select("outcome_id, SUM(amount) AS stake").
+		Where("outcome_id IN ? AND status IN ?", outcomeIDs,
+			[]models.BetStatus{models.BetStatusPending, models.BetStatusWon, models.BetStatusLost}).
+		Group("outcome_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stakes := make(map[uuid.UUID]float64, len(rows))
+	for _, r := range rows {
+		stakes[r.OutcomeID] = r.Stake
+	}
+	return stakes, nil
+}
+
+// fillDividends sets each market's pool and the dividend each outcome would
+// pay if it won outright
+func (s *Service) fillDividends(event *models.Event) error {
+	outcomeIDs := make([]uuid.UUID, 0, len(event.Outcomes))
+	for _, o := range event.Outcomes {
+		outcomeIDs = append(outcomeIDs, o.ID)
+	}
+	if len(outcomeIDs) == 0 {
+		return nil
+	}
+
+	stakes, err := s.poolStakes(s.db, outcomeIDs)
+	if err != nil {
+		return err
+	}
+
+	pools := make(map[uuid.UUID]float64)
+	for _, o := range event.Outcomes {
+		pools[o.MarketID] += stakes[o.ID]
+	}
+
+	fill := func(o *models.EventOutcome) {
+		stake := stakes[o.ID]
+		o.PoolStake = &stake
+		if stake > 0 {
+			dividend := poolDividend(pools[o.MarketID], event.PoolCommission, 1, stake)
+			o.Dividend = &dividend
+		}
+	}
+
+	for i := range event.Outcomes {
+		fill(&event.Outcomes[i])
+	}
+	for i := range event.Markets {
+		m := &event.Markets[i]
+		pool := pools[m.ID]
+		m.Pool = &pool
+		for j := range m.Outcomes {
+			fill(&m.Outcomes[j])
+		}
+	}
+	return nil
+}
+
+// settlePool pays a pool market's winners their share of the pool after
+// commission. Dead heats split the pool by their factors, void outcomes are
+// refunded and taken out of the pool, and if nobody backed a winner every
+// stake is returned.
+func (s *Service) settlePool(tx *gorm.DB, event *models.Event, outcomeIDs []uuid.UUID, results map[uuid.UUID]outcomeResult) error {
+	var bets []models.Bet
+	if err := tx.Where("outcome_id IN ? AND status = ?", outcomeIDs, models.BetStatusPending).Find(&bets).Error; err != nil {
+		return err
+	}
+
+	stakes := make(map[uuid.UUID]float64)
+	pool := 0.0
+	for _, bet := range bets {
+		if results[*bet.OutcomeID].status == models.BetLegStatusVoid {
+			continue
+		}
+		stakes[*bet.OutcomeID] += bet.Amount
+		pool += bet.Amount
+	}
+
+	// Only winners that were backed share the pool
+	shares := 0.0
+	for id, r := range results {
+		if r.status == models.BetLegStatusWon && stakes[id] > 0 {
+			shares += r.factor
+		}
+	}
+
+	now := time.Now()
+
+	for _, bet := range bets {
+		r := results[*bet.OutcomeID]
+		switch {
+		case r.status == models.BetLegStatusVoid || shares == 0:
+			if err := tx.Model(&bet).Updates(map[string]interface{}{
+				"status":     models.BetStatusRefunded,
+				"settled_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := s.credit(tx, bet.UserID, bet.Amount, models.TransactionTypeRefund, bet.ID, "Pool refund: "+event.Name); err != nil {
+				return err
+			}
+
+		case r.status == models.BetLegStatusWon:
+			dividend := poolDividend(pool, event.PoolCommission, r.factor/shares, stakes[*bet.OutcomeID])
+			payout := bet.Amount * dividend
+
+			if err := tx.Model(&bet).Updates(map[string]interface{}{
+				"status":     models.BetStatusWon,
+				"odds":       dividend,
+				"payout":     payout,
+				"settled_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := s.credit(tx, bet.UserID, payout, models.TransactionTypeWin, bet.ID, "Pool win: "+event.Name); err != nil {
+				return err
+			}
+
+		default:
+			if err := tx.Model(&bet).Updates(map[string]interface{}{
+				"status":     models.BetStatusLost,
+				"settled_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// poolDividend is the return per unit staked on an outcome holding share of
+// the pool after commission, rounded down to the cent with breakage kept by
+// the house
+func poolDividend(pool, commission, share, stake float64) float64 {
+	return math.Floor(pool*(1-commission)*share/stake*100) / 100
+}
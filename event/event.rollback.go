@@ -0,0 +1,213 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Rollback reverses an event's settlement: payouts are clawed back, bets return
+// to pending and the event goes back to live awaiting a new result (admin only).
+// Clawbacks may leave a user's balance negative; that balance is the debt owed.
+func (s *Service) Rollback(eventID, adminID uuid.UUID, req *RollbackRequest) error {
+	event, err := s.getSettledEvent(eventID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.rollbackEvent(tx, event); err != nil {
+			return err
+		}
+		return s.audit(tx, eventID, adminID, models.SettlementActionRollback, req.Reason, req)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishStatus(eventID, event.Status)
+	return nil
+}
+
+// Resettle rolls back an event's settlement and settles it again with new results (admin only)
+func (s *Service) Resettle(eventID, adminID uuid.UUID, req *ResettleRequest) error {
+	event, err := s.getSettledEvent(eventID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.rollbackEvent(tx, event); err != nil {
+			return err
+		}
+		if err := s.settleEvent(tx, event, req.Results, false); err != nil {
+			return err
+		}
+		return s.audit(tx, eventID, adminID, models.SettlementActionResettle, req.Reason, req)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishStatus(eventID, event.Status)
+	return nil
+}
+
+// GetSettlementAudit returns the settlement history of an event (admin only)
+func (s *Service) GetSettlementAudit(eventID uuid.UUID) ([]models.SettlementAudit, error) {
+	var audits []models.SettlementAudit
+	if err := s.db.Where("event_id = ?", eventID).Order("created_at ASC").Find(&audits).Error; err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
+
+func (s *Service) getSettledEvent(eventID uuid.UUID) (*models.Event, error) {
+	var event models.Event
+	if err := s.db.First(&event, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	if event.Status != models.EventStatusCompleted {
+		return nil, ErrEventNotSettled
+	}
+	return &event, nil
+}
+
+// rollbackEvent undoes settleEvent. Cashed out bets are left alone since they
+// were closed before any result.
+func (s *Service) rollbackEvent(tx *gorm.DB, event *models.Event) error {
+	reset := map[string]interface{}{
+		"status":     models.BetStatusPending,
+		"payout":     0,
+		"settled_at": nil,
+	}
+
+	// Single bets
+	var bets []models.Bet
+	if err := tx.Where("event_id = ? AND type = ? AND status IN ?", event.ID, models.BetTypeEvent,
+		[]models.BetStatus{models.BetStatusWon, models.BetStatusLost, models.BetStatusRefunded}).Find(&bets).Error; err != nil {
+		return err
+	}
+
+	singleReset := reset
+	if event.Mode == models.EventModePool {
+		// Pool odds come from the dividend and are worked out again on settlement
+		singleReset = map[string]interface{}{"odds": 0}
+		for k, v := range reset {
+			singleReset[k] = v
+		}
+	}
+
+	for _, bet := range bets {
+		if err := s.reverseBet(tx, &bet, event.Name); err != nil {
+			return err
+		}
+		if err := tx.Model(&bet).Updates(singleReset).Error; err != nil {
+			return err
+		}
+	}
+
+	// Accumulator legs and their parent bets
+	var legs []models.BetLeg
+	if err := tx.Where("event_id = ? AND status IN ?", event.ID,
+		[]models.BetLegStatus{models.BetLegStatusWon, models.BetLegStatusLost, models.BetLegStatusVoid, models.BetLegStatusPartial}).Find(&legs).Error; err != nil {
+		return err
+	}
+
+	betIDs := make(map[uuid.UUID]bool)
+	for _, leg := range legs {
+		if err := tx.Model(&leg).Updates(map[string]interface{}{
+			"status":           models.BetLegStatusPending,
+			"dead_heat_factor": 1,
+			"refund_fraction":  0,
+			"settled_at":       nil,
+		}).Error; err != nil {
+			return err
+		}
+		betIDs[leg.BetID] = true
+	}
+
+	for betID := range betIDs {
+		var bet models.Bet
+		if err := tx.First(&bet, "id = ?", betID).Error; err != nil {
+			return err
+		}
+		if bet.Status == models.BetStatusCashedOut {
+			continue
+		}
+		if err := s.reverseBet(tx, &bet, event.Name); err != nil {
+			return err
+		}
+		if err := tx.Model(&bet).Updates(reset).Error; err != nil {
+			return err
+		}
+		// Recalculate odds now that the leg is pending again
+		if err := s.resolveAccumulator(tx, betID); err != nil {
+			return err
+		}
+	}
+
+	// Outcomes, markets and the event itself
+	if err := tx.Model(&models.EventOutcome{}).Where("event_id = ?", event.ID).Updates(map[string]interface{}{
+		"is_winner":        nil,
+		"is_void":          false,
+		"dead_heat_factor": nil,
+		"refund_fraction":  nil,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.EventMarket{}).
+		Where("event_id = ? AND status = ?", event.ID, models.MarketStatusSettled).
+		Update("status", models.MarketStatusSuspended).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(event).Update("status", models.EventStatusLive).Error; err != nil {
+		return err
+	}
+	event.Status = models.EventStatusLive
+	return nil
+}
+
+// reverseBet claws back whatever a settled bet paid out
+func (s *Service) reverseBet(tx *gorm.DB, bet *models.Bet, eventName string) error {
+	var amount float64
+	switch bet.Status {
+	case models.BetStatusWon:
+		amount = bet.Payout
+	case models.BetStatusRefunded:
+		// a partial refund records what came back as the payout
+		amount = bet.Amount
+		if bet.Payout > 0 {
+			amount = bet.Payout
+		}
+	}
+	if amount <= 0 {
+		return nil
+	}
+	return s.credit(tx, bet.UserID, -amount, models.TransactionTypeReversal, bet.ID, "Settlement reversed: "+eventName)
+}
+
+// audit records a settlement action against an event
+func (s *Service) audit(tx *gorm.DB, eventID, adminID uuid.UUID, action models.SettlementAction, reason string, details interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.SettlementAudit{
+		ID:      uuid.New(),
+		EventID: eventID,
+		AdminID: adminID,
+		Action:  action,
+		Reason:  reason,
+		Details: string(data),
+	}).Error
+}
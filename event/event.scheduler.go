@@ -0,0 +1,79 @@
+package event
+
+import (
+	"log"
+	"time"
+
+	"gamba/chat"
+	"gamba/models"
+
+	"github.com/google/uuid"
+)
+
+// RunScheduler moves events through their lifecycle on a fixed interval
+func (s *Service) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.startDueEvents()
+		s.flagOverdueEvents()
+	}
+}
+
+// startDueEvents moves upcoming events to live once StartsAt has passed
+func (s *Service) startDueEvents() {
+	var events []models.Event
+	if err := s.db.Where("status = ? AND starts_at <= ?", models.EventStatusUpcoming, time.Now()).Find(&events).Error; err != nil {
+		log.Printf("Error loading due events: %v", err)
+		return
+	}
+
+	for _, event := range events {
+		// Only transition if nobody changed the status in the meantime
+		result := s.db.Model(&models.Event{}).
+			Where("id = ? AND status = ?", event.ID, models.EventStatusUpcoming).
+			Update("status", models.EventStatusLive)
+		if result.Error != nil {
+			log.Printf("Error starting event %s: %v", event.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected > 0 {
+			s.publishStatus(event.ID, models.EventStatusLive)
+		}
+	}
+}
+
+// flagOverdueEvents marks live events past EndsAt that still have no result
+func (s *Service) flagOverdueEvents() {
+	result := s.db.Model(&models.Event{}).
+		Where("status = ? AND ends_at IS NOT NULL AND ends_at <= ? AND result_overdue = ?", models.EventStatusLive, time.Now(), false).
+		Update("result_overdue", true)
+	if result.Error != nil {
+		log.Printf("Error flagging overdue events: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Flagged %d events past their end time without a result", result.RowsAffected)
+	}
+}
+
+// GetOverdue returns events flagged as needing a result (admin only)
+func (s *Service) GetOverdue() ([]models.Event, error) {
+	var events []models.Event
+	if err := s.db.Preload("Outcomes").Where("result_overdue = ?", true).Order("ends_at ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// publishStatus pushes an event status change to its WebSocket subscribers
+func (s *Service) publishStatus(eventID uuid.UUID, status models.EventStatus) {
+	s.broadcast(eventID, chat.WSMessage{
+		Type: "event_status",
+		Payload: EventStatusChange{
+			EventID: eventID,
+			Status:  status,
+		},
+	})
+}
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"time"
 
+	"gamba/chat"
 	"gamba/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -17,20 +19,64 @@ var (
 	ErrEventAlreadySettled = errors.New("event is already settled")
 	ErrInsufficientFunds   = errors.New("insufficient funds")
 	ErrInvalidAmount       = errors.New("invalid bet amount")
+	ErrTooFewLegs          = errors.New("accumulator needs at least two legs")
+	ErrTooManyLegs         = errors.New("accumulator has too many legs")
+	ErrDuplicateLegEvent   = errors.New("accumulator legs must be on different events")
+	ErrBetNotFound         = errors.New("bet not found")
+	ErrCashOutUnavailable  = errors.New("cash out is not available for this bet")
+	ErrCashOutQuoteChanged = errors.New("cash out value has changed")
+	ErrOddsChanged         = errors.New("odds have changed")
+	ErrMarketNotFound      = errors.New("market not found")
+	ErrMarketSuspended     = errors.New("market is not accepting bets")
+	ErrMarketSettled       = errors.New("market is already settled")
+	ErrMarketUnresolved    = errors.New("every open market needs a result")
+	ErrInvalidMarket       = errors.New("invalid market type or line")
+	ErrMarketFull          = errors.New("market has the maximum number of outcomes")
+	ErrMarketHasBets       = errors.New("market has pending bets")
+	ErrOutcomeHasBets      = errors.New("outcome has pending bets")
+	ErrEventNotSettled     = errors.New("event has not been settled")
+	ErrInvalidResult       = errors.New("invalid settlement result")
+	ErrOutcomeSuspended    = errors.New("outcome is suspended")
+	ErrPoolBetUnsupported  = errors.New("pool events only take single bets")
+	ErrCancelUnavailable   = errors.New("bet can no longer be cancelled")
 )
 
+// Config holds tunable settings for event betting
+type Config struct {
+	CashOutMargin      float64       // fraction kept by the house on cash out, e.g. 0.05
+	BetCutoff          time.Duration // how long before StartsAt betting closes on no in-play events
+	LiabilityThreshold float64       // house loss on a single outcome that suspends it, 0 = no limit
+	OddsAdjustStep     float64       // fraction odds shorten by past half the threshold, 0 = off
+	PoolCommission     float64       // default house cut on pool events, e.g. 0.15
+	CancelGrace        time.Duration // how long after placing a bettor may cancel it, 0 = never
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CashOutMargin:  0.05,
+		BetCutoff:      time.Minute,
+		PoolCommission: 0.15,
+		CancelGrace:    30 * time.Second,
+	}
+}
+
 type Service struct {
-	db *gorm.DB
+	db     *gorm.DB
+	hub    *chat.Hub
+	config Config
 }
 
-func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+func NewService(db *gorm.DB, hub *chat.Hub, config Config) *Service {
+	return &Service{db: db, hub: hub, config: config}
 }
 
+// searchDocument matches the expression index on events for full-text search
+const searchDocument = "to_tsvector('english', name || ' ' || coalesce(description, ''))"
+
 // GetAll returns events with optional filters
 func (s *Service) GetAll(filter *EventFilter) ([]models.Event, error) {
 	var events []models.Event
-	query := s.db.Preload("Outcomes")
+	query := s.db.Preload("Markets.Outcomes").Preload("Outcomes")
 
 	if filter.Status != nil {
 		query = query.Where("status = ?", *filter.Status)
@@ -38,8 +84,40 @@ func (s *Service) GetAll(filter *EventFilter) ([]models.Event, error) {
 	if filter.Category != nil {
 		query = query.Where("category = ?", *filter.Category)
 	}
+	if filter.Query != "" {
+		query = query.Where(searchDocument+" @@ plainto_tsquery('english', ?)", filter.Query)
+	}
+	if filter.From != nil {
+		query = query.Where("starts_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("starts_at <= ?", *filter.To)
+	}
+
+	switch filter.Sort {
+	case "starts_at":
+		query = query.Order("starts_at ASC")
+	case "name":
+		query = query.Order("name ASC")
+	case "-name":
+		query = query.Order("name DESC")
+	case "created_at":
+		query = query.Order("created_at ASC")
+	case "-created_at":
+		query = query.Order("created_at DESC")
+	case "relevance":
+		if filter.Query != "" {
+			query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "ts_rank(" + searchDocument + ", plainto_tsquery('english', ?)) DESC",
+				Vars: []interface{}{filter.Query},
+			}})
+		}
+		query = query.Order("starts_at DESC")
+	default:
+		query = query.Order("starts_at DESC")
+	}
 
-	query = query.Order("starts_at DESC").Limit(filter.Limit).Offset(filter.Offset)
+	query = query.Limit(filter.Limit).Offset(filter.Offset)
 
 	if err := query.Find(&events).Error; err != nil {
 		return nil, err
@@ -50,12 +128,18 @@ func (s *Service) GetAll(filter *EventFilter) ([]models.Event, error) {
 // GetByID returns an event by ID
 func (s *Service) GetByID(id uuid.UUID) (*models.Event, error) {
 	var event models.Event
-	if err := s.db.Preload("Outcomes").First(&event, "id = ?", id).Error; err != nil {
+	if err := s.db.Preload("Markets.Outcomes").Preload("Outcomes").First(&event, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrEventNotFound
 		}
 		return nil, err
 	}
+
+	if event.Mode == models.EventModePool {
+		if err := s.fillDividends(&event); err != nil {
+			return nil, err
+		}
+	}
 	return &event, nil
 }
 
@@ -69,6 +153,15 @@ func (s *Service) Create(req *CreateRequest) (*models.Event, error) {
 		Status:      models.EventStatusUpcoming,
 		StartsAt:    req.StartsAt,
 		EndsAt:      req.EndsAt,
+		NoInPlay:    req.NoInPlay,
+		Mode:        models.EventModeFixed,
+	}
+	if req.Mode == models.EventModePool {
+		event.Mode = models.EventModePool
+		event.PoolCommission = s.config.PoolCommission
+		if req.PoolCommission != nil {
+			event.PoolCommission = *req.PoolCommission
+		}
 	}
 
 	if err := s.db.Create(&event).Error; err != nil {
@@ -106,6 +199,12 @@ func (s *Service) Update(id uuid.UUID, req *UpdateRequest) (*models.Event, error
 	if req.EndsAt != nil {
 		updates["ends_at"] = *req.EndsAt
 	}
+	if req.NoInPlay != nil {
+		updates["no_in_play"] = *req.NoInPlay
+	}
+	if req.PoolCommission != nil {
+		updates["pool_commission"] = *req.PoolCommission
+	}
 
 	if len(updates) > 0 {
 		if err := s.db.Model(&event).Updates(updates).Error; err != nil {
@@ -139,13 +238,27 @@ func (s *Service) AddOutcome(eventID uuid.UUID, req *CreateOutcomeRequest) (*mod
 	}
 
 	outcome := models.EventOutcome{
-		ID:      uuid.New(),
-		EventID: eventID,
-		Name:    req.Name,
-		Odds:    req.Odds,
+		ID:          uuid.New(),
+		EventID:     eventID,
+		Name:        req.Name,
+		Odds:        req.Odds,
+		OddsVersion: 1,
 	}
 
-	if err := s.db.Create(&outcome).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		market, err := s.outcomeMarket(tx, eventID, req.MarketID)
+		if err != nil {
+			return err
+		}
+		outcome.MarketID = market.ID
+
+		if err := tx.Create(&outcome).Error; err != nil {
+			return err
+		}
+		return recordOdds(tx, &outcome)
+	})
+
+	if err != nil {
 		return nil, err
 	}
 	return &outcome, nil
@@ -165,32 +278,111 @@ func (s *Service) UpdateOutcome(outcomeID uuid.UUID, req *UpdateOutcomeRequest)
 	if req.Name != nil {
 		updates["name"] = *req.Name
 	}
-	if req.Odds != nil {
+	oddsChanged := req.Odds != nil && *req.Odds != outcome.Odds
+	if oddsChanged {
 		updates["odds"] = *req.Odds
+		updates["odds_version"] = gorm.Expr("odds_version + 1")
+		updates["odds_pinned"] = true
+	}
+	if req.OddsPinned != nil {
+		updates["odds_pinned"] = *req.OddsPinned
 	}
 	if req.IsWinner != nil {
 		updates["is_winner"] = *req.IsWinner
 	}
+	if req.IsSuspended != nil {
+		updates["is_suspended"] = *req.IsSuspended
+	}
 
 	if len(updates) > 0 {
-		if err := s.db.Model(&outcome).Updates(updates).Error; err != nil {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&outcome).Updates(updates).Error; err != nil {
+				return err
+			}
+			if !oddsChanged {
+				return nil
+			}
+			// re-read the version the increment produced
+			if err := tx.First(&outcome, "id = ?", outcome.ID).Error; err != nil {
+				return err
+			}
+			return recordOdds(tx, &outcome)
+		})
+		if err != nil {
 			return nil, err
 		}
 	}
 
+	if oddsChanged {
+		s.publishOdds(&outcome)
+	}
+
 	return &outcome, nil
 }
 
-// DeleteOutcome deletes an outcome (admin only)
-func (s *Service) DeleteOutcome(outcomeID uuid.UUID) error {
-	result := s.db.Delete(&models.EventOutcome{}, "id = ?", outcomeID)
-	if result.Error != nil {
-		return result.Error
+// GetOutcome returns an outcome by ID
+func (s *Service) GetOutcome(outcomeID uuid.UUID) (*models.EventOutcome, error) {
+	var outcome models.EventOutcome
+	if err := s.db.First(&outcome, "id = ?", outcomeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOutcomeNotFound
+		}
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return ErrOutcomeNotFound
+	return &outcome, nil
+}
+
+// GetOddsHistory returns every odds version of an outcome, oldest first
+func (s *Service) GetOddsHistory(outcomeID uuid.UUID) ([]models.OddsHistory, error) {
+	var history []models.OddsHistory
+	if err := s.db.Where("outcome_id = ?", outcomeID).Order("version ASC").Find(&history).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return history, nil
+}
+
+// publishOdds pushes an odds change to the event's WebSocket subscribers
+func (s *Service) publishOdds(outcome *models.EventOutcome) {
+	s.broadcastAll([]eventMessage{oddsMessage(outcome)})
+}
+
+// oddsMessage builds the odds_changed message for an outcome
+func oddsMessage(outcome *models.EventOutcome) eventMessage {
+	return eventMessage{
+		eventID: outcome.EventID,
+		msg: chat.WSMessage{
+			Type: "odds_changed",
+			Payload: OddsChange{
+				EventID:   outcome.EventID,
+				OutcomeID: outcome.ID,
+				Odds:      outcome.Odds,
+				Version:   outcome.OddsVersion,
+			},
+		},
+	}
+}
+
+// DeleteOutcome deletes an outcome that has no pending bets (admin only)
+func (s *Service) DeleteOutcome(outcomeID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var outcome models.EventOutcome
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&outcome, "id = ?", outcomeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOutcomeNotFound
+			}
+			return err
+		}
+
+		pending, err := hasPendingBets(tx, []uuid.UUID{outcome.ID})
+		if err != nil {
+			return err
+		}
+		if pending {
+			return ErrOutcomeHasBets
+		}
+
+		return tx.Delete(&outcome).Error
+	})
 }
 
 // PlaceBet places a bet on an event outcome
@@ -209,7 +401,7 @@ func (s *Service) PlaceBet(userID uuid.UUID, eventID uuid.UUID, req *PlaceBetReq
 	}
 
 	// Check if event is bettable
-	if event.Status != models.EventStatusUpcoming && event.Status != models.EventStatusLive {
+	if !s.isBettable(&event) {
 		return nil, ErrEventNotBettable
 	}
 
@@ -222,6 +414,20 @@ func (s *Service) PlaceBet(userID uuid.UUID, eventID uuid.UUID, req *PlaceBetReq
 		return nil, err
 	}
 
+	if outcome.IsSuspended {
+		return nil, ErrOutcomeSuspended
+	}
+
+	// Reject bets placed against stale odds
+	pool := event.Mode == models.EventModePool
+	if !pool && req.OddsVersion != nil && *req.OddsVersion != outcome.OddsVersion {
+		return nil, ErrOddsChanged
+	}
+
+	if err := s.checkMarketOpen(s.db, outcome.MarketID); err != nil {
+		return nil, err
+	}
+
 	// Get user
 	var user models.User
 	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -233,6 +439,7 @@ func (s *Service) PlaceBet(userID uuid.UUID, eventID uuid.UUID, req *PlaceBetReq
 	}
 
 	var bet *models.Bet
+	var action liabilityAction
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		newBalance := user.Balance - req.Amount
@@ -242,16 +449,21 @@ func (s *Service) PlaceBet(userID uuid.UUID, eventID uuid.UUID, req *PlaceBetReq
 			return err
 		}
 
-		// Create bet
+		// Create bet, pool bets get their odds from the dividend at settlement
+		odds := outcome.Odds
+		if pool {
+			odds = 0
+		}
 		bet = &models.Bet{
-			ID:        uuid.New(),
-			UserID:    userID,
-			Type:      models.BetTypeEvent,
-			EventID:   &eventID,
-			OutcomeID: &req.OutcomeID,
-			Amount:    req.Amount,
-			Odds:      outcome.Odds,
-			Status:    models.BetStatusPending,
+			ID:          uuid.New(),
+			UserID:      userID,
+			Type:        models.BetTypeEvent,
+			EventID:     &eventID,
+			OutcomeID:   &req.OutcomeID,
+			Amount:      req.Amount,
+			Odds:        odds,
+			OddsVersion: outcome.OddsVersion,
+			Status:      models.BetStatusPending,
 		}
 		if err := tx.Create(bet).Error; err != nil {
 			return err
@@ -268,18 +480,80 @@ func (s *Service) PlaceBet(userID uuid.UUID, eventID uuid.UUID, req *PlaceBetReq
 			ReferenceType: strPtr("bet"),
 			Description:   "Event bet: " + event.Name,
 		}
-		return tx.Create(&transaction).Error
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		if pool {
+			return nil
+		}
+
+		var err error
+		action, err = s.enforceLiability(tx, &outcome)
+		return err
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	s.publishLiability(&outcome, action)
+
 	return bet, nil
 }
 
-// Settle settles an event and pays out winning bets (admin only)
-func (s *Service) Settle(eventID uuid.UUID, req *SettleRequest) error {
+// CancelBet refunds a pending single bet placed within the grace period, as
+// long as its event has not gone live
+func (s *Service) CancelBet(userID, betID uuid.UUID) (*models.Bet, error) {
+	var bet models.Bet
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&bet, "id = ? AND user_id = ?", betID, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBetNotFound
+			}
+			return err
+		}
+
+		if bet.Type != models.BetTypeEvent || bet.Status != models.BetStatusPending ||
+			time.Since(bet.CreatedAt) > s.config.CancelGrace {
+			return ErrCancelUnavailable
+		}
+
+		var event models.Event
+		if err := tx.First(&event, "id = ?", bet.EventID).Error; err != nil {
+			return err
+		}
+		if event.Status != models.EventStatusUpcoming || !time.Now().Before(event.StartsAt) {
+			return ErrCancelUnavailable
+		}
+
+		// Guard against the bet being settled or cashed out concurrently
+		now := time.Now()
+		result := tx.Model(&bet).
+			Where("status = ?", models.BetStatusPending).
+			Updates(map[string]interface{}{
+				"status":     models.BetStatusCancelled,
+				"settled_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCancelUnavailable
+		}
+
+		return s.credit(tx, userID, bet.Amount, models.TransactionTypeRefund, bet.ID, "Bet cancelled: "+event.Name)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return &bet, nil
+}
+
+// Settle settles every open market on an event and completes it (admin only)
+func (s *Service) Settle(eventID, adminID uuid.UUID, req *SettleRequest) error {
 	var event models.Event
 	if err := s.db.First(&event, "id = ?", eventID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -292,82 +566,79 @@ func (s *Service) Settle(eventID uuid.UUID, req *SettleRequest) error {
 		return ErrEventAlreadySettled
 	}
 
-	// Verify outcome belongs to event
-	var winningOutcome models.EventOutcome
-	if err := s.db.First(&winningOutcome, "id = ? AND event_id = ?", req.WinningOutcomeID, eventID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrOutcomeNotFound
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.settleEvent(tx, &event, req.Results, req.Partial); err != nil {
+			return err
 		}
+		return s.audit(tx, eventID, adminID, models.SettlementActionSettle, "", req)
+	})
+	if err != nil {
 		return err
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Mark winning outcome
-		if err := tx.Model(&winningOutcome).Update("is_winner", true).Error; err != nil {
-			return err
-		}
+	if event.Status == models.EventStatusCompleted {
+		s.publishStatus(eventID, event.Status)
+	}
+	return nil
+}
 
-		// Get all pending bets for this event
-		var bets []models.Bet
-		if err := tx.Where("event_id = ? AND status = ?", eventID, models.BetStatusPending).Find(&bets).Error; err != nil {
-			return err
-		}
+// settleEvent settles the event's unsettled markets from the given results and
+// completes it once no market is left open. Partial results leave unlisted
+// markets open.
+func (s *Service) settleEvent(tx *gorm.DB, event *models.Event, marketResults []MarketResult, partial bool) error {
+	var markets []models.EventMarket
+	if err := tx.Where("event_id = ?", event.ID).Find(&markets).Error; err != nil {
+		return err
+	}
 
-		now := time.Now()
+	results := make(map[uuid.UUID]*SettleMarketRequest)
+	for i := range marketResults {
+		results[marketResults[i].MarketID] = &marketResults[i].SettleMarketRequest
+	}
 
-		for _, bet := range bets {
-			if bet.OutcomeID != nil && *bet.OutcomeID == req.WinningOutcomeID {
-				// Winner
-				payout := bet.Amount * bet.Odds
-
-				// Update bet
-				if err := tx.Model(&bet).Updates(map[string]interface{}{
-					"status":     models.BetStatusWon,
-					"payout":     payout,
-					"settled_at": now,
-				}).Error; err != nil {
-					return err
-				}
-
-				// Update user balance
-				var user models.User
-				if err := tx.First(&user, "id = ?", bet.UserID).Error; err != nil {
-					return err
-				}
-
-				newBalance := user.Balance + payout
-				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-					return err
-				}
-
-				// Create win transaction
-				winTx := models.Transaction{
-					ID:            uuid.New(),
-					UserID:        bet.UserID,
-					Type:          models.TransactionTypeWin,
-					Status:        models.TransactionStatusCompleted,
-					Amount:        payout,
-					ReferenceID:   &bet.ID,
-					ReferenceType: strPtr("bet"),
-					Description:   "Event win: " + event.Name,
-				}
-				if err := tx.Create(&winTx).Error; err != nil {
-					return err
-				}
-			} else {
-				// Loser
-				if err := tx.Model(&bet).Updates(map[string]interface{}{
-					"status":     models.BetStatusLost,
-					"settled_at": now,
-				}).Error; err != nil {
-					return err
-				}
+	// Every unsettled market needs a result, and every result a market
+	open := make([]models.EventMarket, 0, len(markets))
+	known := make(map[uuid.UUID]bool)
+	remaining := 0
+	for _, m := range markets {
+		known[m.ID] = true
+		if m.Status == models.MarketStatusSettled || m.Status == models.MarketStatusVoid {
+			continue
+		}
+		if _, ok := results[m.ID]; !ok {
+			if !partial {
+				return ErrMarketUnresolved
 			}
+			remaining++
+			continue
 		}
+		open = append(open, m)
+	}
+	for marketID := range results {
+		if !known[marketID] {
+			return ErrMarketNotFound
+		}
+	}
 
-		// Mark event as completed
-		return tx.Model(&event).Update("status", models.EventStatusCompleted).Error
-	})
+	for i := range open {
+		if err := s.settleMarket(tx, event, &open[i], results[open[i].ID]); err != nil {
+			return err
+		}
+	}
+
+	if remaining > 0 {
+		return nil
+	}
+
+	// Mark event as completed
+	if err := tx.Model(event).Updates(map[string]interface{}{
+		"status":         models.EventStatusCompleted,
+		"result_overdue": false,
+	}).Error; err != nil {
+		return err
+	}
+	event.Status = models.EventStatusCompleted
+	return nil
 }
 
 // Cancel cancels an event and refunds all bets (admin only)
@@ -384,54 +655,100 @@ func (s *Service) Cancel(eventID uuid.UUID) error {
 		return ErrEventAlreadySettled
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Get all pending bets
-		var bets []models.Bet
-		if err := tx.Where("event_id = ? AND status = ?", eventID, models.BetStatusPending).Find(&bets).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.cancelEvent(tx, &event)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishStatus(eventID, models.EventStatusCancelled)
+	return nil
+}
+
+// cancelEvent refunds an event's pending bets, voids its accumulator legs and
+// open markets and marks it cancelled
+func (s *Service) cancelEvent(tx *gorm.DB, event *models.Event) error {
+	// Get all pending bets
+	var bets []models.Bet
+	if err := tx.Where("event_id = ? AND status = ?", event.ID, models.BetStatusPending).Find(&bets).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, bet := range bets {
+		// Update bet
+		if err := tx.Model(&bet).Updates(map[string]interface{}{
+			"status":     models.BetStatusRefunded,
+			"settled_at": now,
+		}).Error; err != nil {
 			return err
 		}
 
-		now := time.Now()
+		// Refund user
+		var user models.User
+		if err := tx.First(&user, "id = ?", bet.UserID).Error; err != nil {
+			return err
+		}
 
-		for _, bet := range bets {
-			// Update bet
-			if err := tx.Model(&bet).Updates(map[string]interface{}{
-				"status":     models.BetStatusRefunded,
-				"settled_at": now,
-			}).Error; err != nil {
-				return err
-			}
+		newBalance := user.Balance + bet.Amount
+		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
+			return err
+		}
 
-			// Refund user
-			var user models.User
-			if err := tx.First(&user, "id = ?", bet.UserID).Error; err != nil {
-				return err
-			}
+		// Create refund transaction
+		refundTx := models.Transaction{
+			ID:            uuid.New(),
+			UserID:        bet.UserID,
+			Type:          models.TransactionTypeRefund,
+			Status:        models.TransactionStatusCompleted,
+			Amount:        bet.Amount,
+			ReferenceID:   &bet.ID,
+			ReferenceType: strPtr("bet"),
+			Description:   "Event cancelled: " + event.Name,
+		}
+		if err := tx.Create(&refundTx).Error; err != nil {
+			return err
+		}
+	}
 
-			newBalance := user.Balance + bet.Amount
-			if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-				return err
-			}
+	// Void accumulator legs on this event
+	var legs []models.BetLeg
+	if err := tx.Where("event_id = ? AND status = ?", event.ID, models.BetLegStatusPending).Find(&legs).Error; err != nil {
+		return err
+	}
+	if err := s.settleLegs(tx, legs, nil); err != nil {
+		return err
+	}
 
-			// Create refund transaction
-			refundTx := models.Transaction{
-				ID:            uuid.New(),
-				UserID:        bet.UserID,
-				Type:          models.TransactionTypeRefund,
-				Status:        models.TransactionStatusCompleted,
-				Amount:        bet.Amount,
-				ReferenceID:   &bet.ID,
-				ReferenceType: strPtr("bet"),
-				Description:   "Event cancelled: " + event.Name,
-			}
-			if err := tx.Create(&refundTx).Error; err != nil {
-				return err
-			}
-		}
+	if err := tx.Model(&models.EventMarket{}).
+		Where("event_id = ? AND status IN ?", event.ID, []models.MarketStatus{models.MarketStatusOpen, models.MarketStatusSuspended}).
+		Update("status", models.MarketStatusVoid).Error; err != nil {
+		return err
+	}
 
-		// Mark event as cancelled
-		return tx.Model(&event).Update("status", models.EventStatusCancelled).Error
-	})
+	// Mark event as cancelled
+	return tx.Model(event).Updates(map[string]interface{}{
+		"status":         models.EventStatusCancelled,
+		"result_overdue": false,
+	}).Error
+}
+
+// isBettable reports whether an event currently accepts bets. Events flagged
+// no in-play stop taking bets BetCutoff before they start.
+func (s *Service) isBettable(event *models.Event) bool {
+	if event.Status != models.EventStatusUpcoming && event.Status != models.EventStatusLive {
+		return false
+	}
+	if event.NoInPlay && !time.Now().Before(event.StartsAt.Add(-s.config.BetCutoff)) {
+		return false
+	}
+	return true
+}
+
+func eventTopic(eventID uuid.UUID) string {
+	return "event:" + eventID.String()
 }
 
 func strPtr(s string) *string {
@@ -26,6 +26,10 @@ func (c *Controller) RegisterAdminRoutes(r *gin.RouterGroup) {
 	r.POST("/games", c.Create)
 	r.PUT("/games/:id", c.Update)
 	r.DELETE("/games/:id", c.Delete)
+
+	r.GET("/stake-limits", c.GetStakeLimits)
+	r.PUT("/stake-limits", c.SetStakeLimit)
+	r.DELETE("/stake-limits/:id", c.DeleteStakeLimit)
 }
 
 func (c *Controller) GetAll(ctx *gin.Context) {
@@ -119,6 +123,57 @@ func (c *Controller) Play(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, result)
 }
 
+func (c *Controller) GetStakeLimits(ctx *gin.Context) {
+	var filter StakeLimitFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter"})
+		return
+	}
+
+	limits, err := c.service.GetStakeLimits(&filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, limits)
+}
+
+func (c *Controller) SetStakeLimit(ctx *gin.Context) {
+	var req SetStakeLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	limit, err := c.service.SetStakeLimit(&req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, limit)
+}
+
+func (c *Controller) DeleteStakeLimit(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := c.service.DeleteStakeLimit(id); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// Machine-readable codes for limit rejections so clients can tell them apart
+var limitErrorCodes = map[error]string{
+	ErrStakeLimitExceeded: "stake_limit_exceeded",
+	ErrExposureCapReached: "exposure_cap_reached",
+	ErrMaxPayoutExceeded:  "max_payout_exceeded",
+}
+
 func handleError(ctx *gin.Context, err error) {
 	switch err {
 	case ErrGameNotFound:
@@ -131,6 +186,10 @@ func handleError(ctx *gin.Context, err error) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case ErrInvalidCategory:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrUserNotFound, ErrStakeLimitNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrStakeLimitExceeded, ErrExposureCapReached, ErrMaxPayoutExceeded:
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": limitErrorCodes[err]})
 	default:
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 	}
@@ -3,22 +3,35 @@ package game
 import "github.com/google/uuid"
 
 type CreateRequest struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Category    string  `json:"category"` // Added category field
-	MinBet      float64 `json:"min_bet"`
-	MaxBet      float64 `json:"max_bet"`
-	HouseEdge   float64 `json:"house_edge"`
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	Category         string  `json:"category"` // Added category field
+	MinBet           float64 `json:"min_bet"`
+	MaxBet           float64 `json:"max_bet"`
+	HouseEdge        float64 `json:"house_edge" binding:"gte=0,lte=100"` // percent
+	DailyExposureCap float64 `json:"daily_exposure_cap"`
 }
 
 type UpdateRequest struct {
-	Name        *string  `json:"name,omitempty"`
-	Description *string  `json:"description,omitempty"`
-	Category    *string  `json:"category,omitempty"` // Added category field
-	Status      *string  `json:"status,omitempty"`
-	MinBet      *float64 `json:"min_bet,omitempty"`
-	MaxBet      *float64 `json:"max_bet,omitempty"`
-	HouseEdge   *float64 `json:"house_edge,omitempty"`
+	Name             *string  `json:"name,omitempty"`
+	Description      *string  `json:"description,omitempty"`
+	Category         *string  `json:"category,omitempty"` // Added category field
+	Status           *string  `json:"status,omitempty"`
+	MinBet           *float64 `json:"min_bet,omitempty"`
+	MaxBet           *float64 `json:"max_bet,omitempty"`
+	HouseEdge        *float64 `json:"house_edge,omitempty" binding:"omitempty,gte=0,lte=100"` // percent
+	DailyExposureCap *float64 `json:"daily_exposure_cap,omitempty"`
+}
+
+type SetStakeLimitRequest struct {
+	UserID   uuid.UUID  `json:"user_id" binding:"required"`
+	GameID   *uuid.UUID `json:"game_id,omitempty"`
+	MaxStake float64    `json:"max_stake" binding:"required,gt=0"`
+}
+
+type StakeLimitFilter struct {
+	UserID *string `form:"user_id"`
+	GameID *string `form:"game_id"`
 }
 
 type PlayRequest struct {
@@ -2,11 +2,11 @@ package game
 
 import (
 	"errors"
-	"fmt"
 	"math/rand"
 	"time"
 
 	"gamba/models"
+	"gamba/tournament"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -19,6 +19,12 @@ var (
 	ErrBetTooLow         = errors.New("bet amount below minimum")
 	ErrBetTooHigh        = errors.New("bet amount above maximum")
 	ErrInvalidCategory   = errors.New("invalid game category")
+	ErrUserNotFound      = errors.New("user not found")
+
+	ErrStakeLimitNotFound = errors.New("stake limit not found")
+	ErrStakeLimitExceeded = errors.New("bet amount exceeds your stake limit")
+	ErrExposureCapReached = errors.New("game daily exposure limit reached")
+	ErrMaxPayoutExceeded  = errors.New("potential payout exceeds the maximum per round")
 )
 
 // Slot symbols
@@ -27,13 +33,18 @@ var symbols = []string{"🍒", "🍋", "🍊", "🍇", "🍓", "⭐", "7️⃣"}
 // Symbol multipliers (index matches symbols array)
 var multipliers = []float64{2, 3, 4, 5, 10, 20, 50}
 
+// Highest multiplier a single dice round can pay (snake eyes or 11)
+const diceMaxMultiplier = 7.0
+
 type Service struct {
-	db *gorm.DB
+	db             *gorm.DB
+	maxRoundPayout float64 // house-wide cap on a single round's payout, 0 = no cap
+	tournaments    *tournament.Service
 }
 
-func NewService(db *gorm.DB) *Service {
+func NewService(db *gorm.DB, maxRoundPayout float64, tournaments *tournament.Service) *Service {
 	rand.Seed(time.Now().UnixNano())
-	return &Service{db: db}
+	return &Service{db: db, maxRoundPayout: maxRoundPayout, tournaments: tournaments}
 }
 
 func (s *Service) GetAll() ([]models.Game, error) {
@@ -63,14 +74,15 @@ func (s *Service) Create(req *CreateRequest) (*models.Game, error) {
 	}
 
 	game := models.Game{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Description: req.Description,
-		Category:    category,
-		Status:      models.GameStatusActive,
-		MinBet:      req.MinBet,
-		MaxBet:      req.MaxBet,
-		HouseEdge:   req.HouseEdge,
+		ID:               uuid.New(),
+		Name:             req.Name,
+		Description:      req.Description,
+		Category:         category,
+		Status:           models.GameStatusActive,
+		MinBet:           req.MinBet,
+		MaxBet:           req.MaxBet,
+		HouseEdge:        req.HouseEdge,
+		DailyExposureCap: req.DailyExposureCap,
 	}
 
 	if err := s.db.Create(&game).Error; err != nil {
@@ -115,6 +127,9 @@ func (s *Service) Update(id uuid.UUID, req *UpdateRequest) (*models.Game, error)
 	if req.HouseEdge != nil {
 		updates["house_edge"] = *req.HouseEdge
 	}
+	if req.DailyExposureCap != nil {
+		updates["daily_exposure_cap"] = *req.DailyExposureCap
+	}
 
 	if len(updates) > 0 {
 		if err := s.db.Model(&game).Updates(updates).Error; err != nil {
@@ -159,6 +174,11 @@ func (s *Service) Play(userID uuid.UUID, req *PlayRequest) (*PlayResponse, error
 		return nil, ErrBetTooHigh
 	}
 
+	// Layered limits are checked before any outcome is drawn
+	if err := s.checkLimits(userID, &game, req.BetAmount); err != nil {
+		return nil, err
+	}
+
 	// Get user
 	var user models.User
 	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -183,6 +203,7 @@ func (s *Service) Play(userID uuid.UUID, req *PlayRequest) (*PlayResponse, error
 	// Update balance in transaction
 	newBalance := user.Balance - req.BetAmount + response.Payout
 
+	var rankChanges []tournament.RankChange
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		// Update user balance
 		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
@@ -240,47 +261,128 @@ func (s *Service) Play(userID uuid.UUID, req *PlayRequest) (*PlayResponse, error
 			}
 		}
 
-		return nil
+		// Rescore the player in any running tournaments on this game
+		var err error
+		rankChanges, err = s.tournaments.RecordPlay(tx, userID, game.ID)
+		return err
 	})
 
 	if err != nil {
 		return nil, err
 	}
-
-	// If the player won, update tournament scores (if participating in any active tournaments)
-	if response.Won && response.Payout > 0 {
-		// This is done outside the transaction so it doesn't block the game play
-		s.updateTournamentScores(userID, game.ID, response.Payout)
-	}
+	s.tournaments.PublishRankChanges(rankChanges)
 
 	response.NewBalance = newBalance
 	return response, nil
 }
 
-// updateTournamentScores adds payout as points to active tournament participants
-func (s *Service) updateTournamentScores(userID uuid.UUID, gameID uuid.UUID, payout float64) {
-	now := time.Now()
-
-	// Find all active tournaments for this game
-	var tournaments []models.Tournament
-	if err := s.db.Where(
-		"game_id = ? AND status = ? AND starts_at <= ? AND ends_at >= ?",
-		gameID,
-		models.TournamentStatusInProgress,
-		now,
-		now,
-	).Find(&tournaments).Error; err != nil {
-		// Log error but don't fail
-		return
-	}
-
-	// Update scores for tournaments where user is a participant
-	for _, tournament := range tournaments {
-		s.db.Model(&models.TournamentParticipant{}).
-			Where("tournament_id = ? AND user_id = ?", tournament.ID, userID).
-			Update("score", gorm.Expr("score + ?", payout))
-		fmt.Println(payout)
+// checkLimits enforces the user's stake overrides, the house-wide max payout
+// per round and the game's daily exposure cap
+func (s *Service) checkLimits(userID uuid.UUID, game *models.Game, amount float64) error {
+	var limits []models.StakeLimit
+	if err := s.db.Where("user_id = ? AND (game_id = ? OR game_id IS NULL)", userID, game.ID).Find(&limits).Error; err != nil {
+		return err
+	}
+	for _, limit := range limits {
+		if amount > limit.MaxStake {
+			return ErrStakeLimitExceeded
+		}
+	}
+
+	maxPayout := amount * maxMultiplier(game.Category)
+	if s.maxRoundPayout > 0 && maxPayout > s.maxRoundPayout {
+		return ErrMaxPayoutExceeded
+	}
+
+	if game.DailyExposureCap > 0 {
+		// Net house loss on this game since midnight UTC
+		var exposure float64
+		if err := s.db.Model(&models.Bet{}).
+			Where("game_id = ? AND created_at >= ?", game.ID, time.Now().UTC().Truncate(24*time.Hour)).
+			Select("COALESCE(SUM(payout - amount), 0)").
+			Scan(&exposure).Error; err != nil {
+			return err
+		}
+		if exposure+maxPayout-amount > game.DailyExposureCap {
+			return ErrExposureCapReached
+		}
 	}
+
+	return nil
+}
+
+// GetStakeLimits returns stake limits with optional filters (admin only)
+func (s *Service) GetStakeLimits(filter *StakeLimitFilter) ([]models.StakeLimit, error) {
+	var limits []models.StakeLimit
+	query := s.db.Model(&models.StakeLimit{})
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.GameID != nil {
+		query = query.Where("game_id = ?", *filter.GameID)
+	}
+
+	if err := query.Order("created_at DESC").Find(&limits).Error; err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// SetStakeLimit creates or replaces a user's stake limit (admin only)
+func (s *Service) SetStakeLimit(req *SetStakeLimitRequest) (*models.StakeLimit, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	query := s.db.Where("user_id = ?", req.UserID)
+	if req.GameID != nil {
+		if _, err := s.GetByID(*req.GameID); err != nil {
+			return nil, err
+		}
+		query = query.Where("game_id = ?", *req.GameID)
+	} else {
+		query = query.Where("game_id IS NULL")
+	}
+
+	var limit models.StakeLimit
+	err := query.First(&limit).Error
+	if err == nil {
+		if err := s.db.Model(&limit).Update("max_stake", req.MaxStake).Error; err != nil {
+			return nil, err
+		}
+		return &limit, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	limit = models.StakeLimit{
+		ID:       uuid.New(),
+		UserID:   req.UserID,
+		GameID:   req.GameID,
+		MaxStake: req.MaxStake,
+	}
+	if err := s.db.Create(&limit).Error; err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// DeleteStakeLimit removes a stake limit (admin only)
+func (s *Service) DeleteStakeLimit(id uuid.UUID) error {
+	result := s.db.Delete(&models.StakeLimit{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStakeLimitNotFound
+	}
+	return nil
 }
 
 // playSlots handles slot machine game logic
@@ -323,7 +425,7 @@ func (s *Service) playDice(betAmount float64) *PlayResponse {
 		multiplier = 4.0
 	} else if total == 11 || total == 2 { // Snake eyes (2) or 11
 		won = true
-		multiplier = 7.0
+		multiplier = diceMaxMultiplier
 	} else if dice[0] == dice[1] { // Doubles (except snake eyes which is handled above)
 		won = true
 		multiplier = 3.0
@@ -387,6 +489,24 @@ func (s *Service) calculateSlotWin(reels [3]string) (bool, float64) {
 	return false, 0
 }
 
+// maxMultiplier returns the highest multiplier a single round of the category can pay
+func maxMultiplier(category models.GameCategory) float64 {
+	switch category {
+	case models.GameCategorySlots:
+		highest := 0.0
+		for _, m := range multipliers {
+			if m > highest {
+				highest = m
+			}
+		}
+		return highest
+	case models.GameCategoryDice:
+		return diceMaxMultiplier
+	default:
+		return 0
+	}
+}
+
 // Helper functions
 func getGameDescription(category models.GameCategory) string {
 	switch category {
@@ -0,0 +1,104 @@
+// Command importer loads an event or results feed into the database.
+//
+//	go run ./importer -file events.csv
+//	go run ./importer -file results.json -results -admin <user id> -dry-run
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gamba/event"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func constructDsn() string {
+	err := godotenv.Load()
+
+	if err != nil {
+		log.Println(".env file not found, using system environment variables")
+	}
+	host := os.Getenv("DB_HOST")
+	user := os.Getenv("DB_USER")
+	password := os.Getenv("DB_PASS")
+	dbname := os.Getenv("DB_NAME")
+	sslmode := os.Getenv("DB_SSLMODE")
+	channelBinding := os.Getenv("DB_CHANNELBINDING")
+
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s sslmode=%s channelbinding=%s",
+		host, user, password, dbname, sslmode, channelBinding,
+	)
+}
+
+func main() {
+	file := flag.String("file", "", "feed to import (.json or .csv)")
+	results := flag.Bool("results", false, "the feed holds results to settle or cancel events with")
+	admin := flag.String("admin", "", "admin user id recorded against settlements from a results feed")
+	dryRun := flag.Bool("dry-run", false, "report changes without saving them")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	format := event.FormatJSON
+	if strings.EqualFold(filepath.Ext(*file), ".csv") {
+		format = event.FormatCSV
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("failed to open feed:", err)
+	}
+	defer f.Close()
+
+	db, err := gorm.Open(postgres.Open(constructDsn()), &gorm.Config{})
+	if err != nil {
+		log.Fatal("failed to connect to database:", err)
+	}
+
+	// No hub, odds changes are not pushed to websocket clients from here
+	service := event.NewService(db, nil, event.DefaultConfig())
+
+	var report *event.ImportReport
+	if *results {
+		adminID, err := uuid.Parse(*admin)
+		if err != nil {
+			log.Fatal("-admin must be a user id for a results feed")
+		}
+
+		feed, err := event.ParseResultsFeed(f, format)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err = service.ImportResults(feed, adminID, *dryRun)
+		if err != nil {
+			log.Fatal("import failed:", err)
+		}
+	} else {
+		feed, err := event.ParseEventFeed(f, format)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err = service.ImportEvents(feed, *dryRun)
+		if err != nil {
+			log.Fatal("import failed:", err)
+		}
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -15,15 +15,27 @@ func main() {
 		&models.Chat{},
 		&models.Game{},
 		&models.Event{},
+		&models.EventMarket{},
 		&models.EventOutcome{},
+		&models.OddsHistory{},
+		&models.SettlementAudit{},
+		&models.EventFavourite{},
+		&models.EventSubscription{},
 		&models.RefreshToken{},
 		&models.Tournament{},
 		&models.TournamentParticipant{},
+		&models.TournamentPrize{},
+		&models.TournamentMatch{},
+		&models.TournamentMatchRound{},
+		&models.TournamentTemplate{},
 		&models.Bet{},
+		&models.BetLeg{},
 		&models.Ticket{},
 		&models.TicketMessage{},
 		&models.Transaction{},
 		&models.Friend{},
+		&models.StakeLimit{},
+		&models.GamePlayerDailyStat{},
 	)
 	if err != nil {
 		panic(err)
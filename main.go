@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"gamba/analytics"
 	"gamba/auth"
 	"gamba/bet"
 	"gamba/chat"
@@ -13,6 +14,7 @@ import (
 	"gamba/user"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -41,6 +43,56 @@ func constructDsn() string {
 	)
 }
 
+// oddsProvider picks the odds feed from the environment, if one is configured
+func oddsProvider() event.OddsProvider {
+	if url := os.Getenv("ODDS_FEED_URL"); url != "" {
+		return event.NewHTTPProvider(url)
+	}
+	if path := os.Getenv("ODDS_FEED_FILE"); path != "" {
+		return event.NewFileProvider(path)
+	}
+	return nil
+}
+
+// envFloat reads a number from the environment, fallback when unset
+func envFloat(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("invalid %s %q: %v", name, value, err)
+	}
+	return n
+}
+
+// envInt reads a whole number from the environment, fallback when unset
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid %s %q: %v", name, value, err)
+	}
+	return n
+}
+
+// envDuration reads a duration such as "90s" from the environment, fallback when unset
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s %q: %v", name, value, err)
+	}
+	return d
+}
+
 func main() {
 	dsn := constructDsn()
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
@@ -65,11 +117,30 @@ func main() {
 	ticketService := ticket.NewService(db)
 	chatService := chat.NewService(db, hub)
 	userService := user.NewService(db)
-	gameService := game.NewService(db)
-	eventService := event.NewService(db)
+	eventConfig := event.DefaultConfig()
+	eventConfig.CashOutMargin = envFloat("EVENT_CASH_OUT_MARGIN", eventConfig.CashOutMargin)
+	eventConfig.BetCutoff = envDuration("EVENT_BET_CUTOFF", eventConfig.BetCutoff)
+	eventConfig.LiabilityThreshold = envFloat("EVENT_LIABILITY_THRESHOLD", eventConfig.LiabilityThreshold)
+	eventConfig.OddsAdjustStep = envFloat("EVENT_ODDS_ADJUST_STEP", eventConfig.OddsAdjustStep)
+	eventConfig.CancelGrace = envDuration("EVENT_BET_CANCEL_GRACE", eventConfig.CancelGrace)
+	eventService := event.NewService(db, hub, eventConfig)
 	betService := bet.NewService(db)
 	transactionService := transaction.NewService(db)
-	tournamentsService := tournament.NewService(db)
+	tournamentConfig := tournament.DefaultConfig()
+	tournamentConfig.MinParticipants = envInt("TOURNAMENT_MIN_PARTICIPANTS", tournamentConfig.MinParticipants)
+	tournamentConfig.PoolShare = envFloat("TOURNAMENT_POOL_SHARE", tournamentConfig.PoolShare)
+	tournamentConfig.SpawnAhead = envDuration("TOURNAMENT_SPAWN_AHEAD", tournamentConfig.SpawnAhead)
+	tournamentsService := tournament.NewService(db, hub, tournamentConfig)
+	gameService := game.NewService(db, envFloat("GAME_MAX_ROUND_PAYOUT", 0), tournamentsService)
+	analyticsService := analytics.NewService(db)
+
+	// Background jobs
+	go analyticsService.RunRollups(15 * time.Minute)
+	go eventService.RunScheduler(30 * time.Second)
+	go tournamentsService.RunScheduler(30 * time.Second)
+	if provider := oddsProvider(); provider != nil {
+		go eventService.RunOddsFeed(provider, envDuration("ODDS_FEED_INTERVAL", time.Minute))
+	}
 
 	// Controllers
 	authController := auth.NewAuthController(authService)
@@ -81,6 +152,7 @@ func main() {
 	betController := bet.NewController(betService)
 	transactionController := transaction.NewController(transactionService)
 	tournamentController := tournament.NewController(tournamentsService)
+	analyticsController := analytics.NewController(analyticsService)
 
 	// Public routes
 	authRoutes := r.Group("/api/auth")
@@ -118,6 +190,7 @@ func main() {
 		betController.RegisterAdminRoutes(admin)
 		transactionController.RegisterAdminRoutes(admin)
 		tournamentController.RegisterAdminRoutes(admin)
+		analyticsController.RegisterAdminRoutes(admin)
 	}
 
 	r.Run(":8080")
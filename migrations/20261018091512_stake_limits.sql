@@ -0,0 +1,16 @@
+-- Modify "games" table
+ALTER TABLE "public"."games" ADD COLUMN "daily_exposure_cap" numeric NULL DEFAULT 0;
+-- Create "stake_limits" table
+CREATE TABLE "public"."stake_limits" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "game_id" uuid NULL,
+  "max_stake" numeric NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_stake_limits_game" FOREIGN KEY ("game_id") REFERENCES "public"."games" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_stake_limits_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_stake_limit_user_game" to table: "stake_limits"
+CREATE UNIQUE INDEX "idx_stake_limit_user_game" ON "public"."stake_limits" ("user_id", "game_id");
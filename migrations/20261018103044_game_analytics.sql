@@ -0,0 +1,15 @@
+-- Create index "idx_bets_created_at" to table: "bets"
+CREATE INDEX "idx_bets_created_at" ON "public"."bets" ("created_at");
+-- Create "game_player_daily_stats" table
+CREATE TABLE "public"."game_player_daily_stats" (
+  "game_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "day" date NOT NULL,
+  "rounds" bigint NOT NULL DEFAULT 0,
+  "handle" numeric NOT NULL DEFAULT 0,
+  "payouts" numeric NOT NULL DEFAULT 0,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("game_id", "user_id", "day"),
+  CONSTRAINT "fk_game_player_daily_stats_game" FOREIGN KEY ("game_id") REFERENCES "public"."games" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_game_player_daily_stats_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
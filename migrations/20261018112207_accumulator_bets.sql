@@ -0,0 +1,20 @@
+-- Create "bet_legs" table
+CREATE TABLE "public"."bet_legs" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "bet_id" uuid NOT NULL,
+  "event_id" uuid NOT NULL,
+  "outcome_id" uuid NOT NULL,
+  "odds" numeric NOT NULL,
+  "status" character varying(20) NULL DEFAULT 'pending',
+  "settled_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_bet_legs_event" FOREIGN KEY ("event_id") REFERENCES "public"."events" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_bet_legs_outcome" FOREIGN KEY ("outcome_id") REFERENCES "public"."event_outcomes" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_bets_legs" FOREIGN KEY ("bet_id") REFERENCES "public"."bets" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_bet_legs_bet_id" to table: "bet_legs"
+CREATE INDEX "idx_bet_legs_bet_id" ON "public"."bet_legs" ("bet_id");
+-- Create index "idx_bet_legs_event_id" to table: "bet_legs"
+CREATE INDEX "idx_bet_legs_event_id" ON "public"."bet_legs" ("event_id");
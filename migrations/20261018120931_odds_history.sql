@@ -0,0 +1,24 @@
+-- Modify "bet_legs" table
+ALTER TABLE "public"."bet_legs" ADD COLUMN "odds_version" bigint NOT NULL DEFAULT 1;
+-- Modify "bets" table
+ALTER TABLE "public"."bets" ADD COLUMN "odds_version" bigint NULL DEFAULT 0;
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ADD COLUMN "odds_version" bigint NOT NULL DEFAULT 1;
+-- Create "odds_history" table
+CREATE TABLE "public"."odds_history" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "outcome_id" uuid NOT NULL,
+  "event_id" uuid NOT NULL,
+  "version" bigint NOT NULL,
+  "odds" numeric NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_odds_history_outcome" FOREIGN KEY ("outcome_id") REFERENCES "public"."event_outcomes" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_odds_history_event_id" to table: "odds_history"
+CREATE INDEX "idx_odds_history_event_id" ON "public"."odds_history" ("event_id");
+-- Create index "idx_odds_history_version" to table: "odds_history"
+CREATE UNIQUE INDEX "idx_odds_history_version" ON "public"."odds_history" ("outcome_id", "version");
+-- Seed version 1 for existing outcomes
+INSERT INTO "public"."odds_history" ("outcome_id", "event_id", "version", "odds", "created_at")
+SELECT "id", "event_id", 1, "odds", NOW() FROM "public"."event_outcomes";
@@ -0,0 +1,2 @@
+-- Modify "events" table
+ALTER TABLE "public"."events" ADD COLUMN "no_in_play" boolean NULL DEFAULT false, ADD COLUMN "result_overdue" boolean NULL DEFAULT false;
@@ -0,0 +1,32 @@
+-- Create "event_markets" table
+CREATE TABLE "public"."event_markets" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "event_id" uuid NOT NULL,
+  "name" text NOT NULL,
+  "type" character varying(20) NOT NULL,
+  "line" numeric NULL,
+  "status" character varying(20) NULL DEFAULT 'open',
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_events_markets" FOREIGN KEY ("event_id") REFERENCES "public"."events" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_event_markets_deleted_at" to table: "event_markets"
+CREATE INDEX "idx_event_markets_deleted_at" ON "public"."event_markets" ("deleted_at");
+-- Create index "idx_event_markets_event_id" to table: "event_markets"
+CREATE INDEX "idx_event_markets_event_id" ON "public"."event_markets" ("event_id");
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ADD COLUMN "market_id" uuid NULL;
+-- Move existing outcomes into a winner market per event
+INSERT INTO "public"."event_markets" ("event_id", "name", "type", "status", "created_at", "updated_at")
+SELECT "id", 'Winner', 'winner',
+  CASE "status" WHEN 'completed' THEN 'settled' WHEN 'cancelled' THEN 'void' ELSE 'open' END,
+  NOW(), NOW()
+FROM "public"."events";
+UPDATE "public"."event_outcomes" AS o SET "market_id" = m."id"
+FROM "public"."event_markets" AS m WHERE m."event_id" = o."event_id";
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ALTER COLUMN "market_id" SET NOT NULL, ADD CONSTRAINT "fk_event_markets_outcomes" FOREIGN KEY ("market_id") REFERENCES "public"."event_markets" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION;
+-- Create index "idx_event_outcomes_market_id" to table: "event_outcomes"
+CREATE INDEX "idx_event_outcomes_market_id" ON "public"."event_outcomes" ("market_id");
@@ -0,0 +1,15 @@
+-- Create "settlement_audits" table
+CREATE TABLE "public"."settlement_audits" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "event_id" uuid NOT NULL,
+  "admin_id" uuid NOT NULL,
+  "action" character varying(20) NOT NULL,
+  "reason" text NULL,
+  "details" text NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_settlement_audits_admin" FOREIGN KEY ("admin_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "fk_settlement_audits_event" FOREIGN KEY ("event_id") REFERENCES "public"."events" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_settlement_audits_event_id" to table: "settlement_audits"
+CREATE INDEX "idx_settlement_audits_event_id" ON "public"."settlement_audits" ("event_id");
@@ -0,0 +1,4 @@
+-- Modify "bet_legs" table
+ALTER TABLE "public"."bet_legs" ADD COLUMN "dead_heat_factor" numeric NOT NULL DEFAULT 1;
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ADD COLUMN "is_void" boolean NULL DEFAULT false, ADD COLUMN "dead_heat_factor" numeric NULL;
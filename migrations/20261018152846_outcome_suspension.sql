@@ -0,0 +1,2 @@
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ADD COLUMN "is_suspended" boolean NULL DEFAULT false;
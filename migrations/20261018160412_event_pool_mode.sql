@@ -0,0 +1,2 @@
+-- Modify "events" table
+ALTER TABLE "public"."events" ADD COLUMN "mode" character varying(20) NULL DEFAULT 'fixed', ADD COLUMN "pool_commission" numeric NULL DEFAULT 0;
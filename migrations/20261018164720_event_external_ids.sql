@@ -0,0 +1,12 @@
+-- Modify "events" table
+ALTER TABLE "public"."events" ADD COLUMN "external_id" character varying(100) NULL;
+-- Create index "idx_events_external_id" to table: "events"
+CREATE UNIQUE INDEX "idx_events_external_id" ON "public"."events" ("external_id");
+-- Modify "event_markets" table
+ALTER TABLE "public"."event_markets" ADD COLUMN "external_id" character varying(100) NULL;
+-- Create index "idx_event_markets_external_id" to table: "event_markets"
+CREATE UNIQUE INDEX "idx_event_markets_external_id" ON "public"."event_markets" ("external_id");
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ADD COLUMN "external_id" character varying(100) NULL;
+-- Create index "idx_event_outcomes_external_id" to table: "event_outcomes"
+CREATE UNIQUE INDEX "idx_event_outcomes_external_id" ON "public"."event_outcomes" ("external_id");
@@ -0,0 +1,24 @@
+-- Create "event_favourites" table
+CREATE TABLE "public"."event_favourites" (
+  "user_id" uuid NOT NULL,
+  "event_id" uuid NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("user_id", "event_id"),
+  CONSTRAINT "fk_event_favourites_event" FOREIGN KEY ("event_id") REFERENCES "public"."events" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_event_favourites_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_event_favourites_event_id" to table: "event_favourites"
+CREATE INDEX "idx_event_favourites_event_id" ON "public"."event_favourites" ("event_id");
+-- Create "event_subscriptions" table
+CREATE TABLE "public"."event_subscriptions" (
+  "user_id" uuid NOT NULL,
+  "event_id" uuid NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("user_id", "event_id"),
+  CONSTRAINT "fk_event_subscriptions_event" FOREIGN KEY ("event_id") REFERENCES "public"."events" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_event_subscriptions_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_event_subscriptions_event_id" to table: "event_subscriptions"
+CREATE INDEX "idx_event_subscriptions_event_id" ON "public"."event_subscriptions" ("event_id");
+-- Full-text search on events, must match searchDocument in event.service.go
+CREATE INDEX "idx_events_search" ON "public"."events" USING GIN (to_tsvector('english', "name" || ' ' || coalesce("description", '')));
@@ -0,0 +1,2 @@
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ADD COLUMN "min_participants" bigint NULL DEFAULT 0, ADD COLUMN "registration_at" timestamptz NULL;
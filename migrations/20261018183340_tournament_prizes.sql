@@ -0,0 +1,23 @@
+-- Modify "tournament_participants" table
+ALTER TABLE "public"."tournament_participants" ADD COLUMN "award" text NULL;
+-- Create "tournament_prizes" table
+CREATE TABLE "public"."tournament_prizes" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "tournament_id" uuid NOT NULL,
+  "rank_from" bigint NOT NULL,
+  "rank_to" bigint NOT NULL,
+  "type" character varying(20) NOT NULL,
+  "amount" numeric NOT NULL,
+  "description" text NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_tournaments_prizes" FOREIGN KEY ("tournament_id") REFERENCES "public"."tournaments" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_tournament_prizes_tournament_id" to table: "tournament_prizes"
+CREATE INDEX "idx_tournament_prizes_tournament_id" ON "public"."tournament_prizes" ("tournament_id");
+-- Give existing tournaments the previous fixed 50/30/20 split
+INSERT INTO "public"."tournament_prizes" ("tournament_id", "rank_from", "rank_to", "type", "amount", "created_at")
+SELECT t."id", p."rank", p."rank", 'percentage', p."amount", NOW()
+FROM "public"."tournaments" AS t
+CROSS JOIN (VALUES (1, 50), (2, 30), (3, 20)) AS p ("rank", "amount")
+WHERE p."rank" <= t."max_participants";
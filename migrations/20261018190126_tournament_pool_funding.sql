@@ -0,0 +1,4 @@
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ADD COLUMN "pool_share" numeric NULL DEFAULT 1, ADD COLUMN "guaranteed_pool" numeric NULL DEFAULT 0, ADD COLUMN "overlay" numeric NULL DEFAULT 0;
+-- Existing tournaments were never funded from entry fees
+UPDATE "public"."tournaments" SET "pool_share" = 0;
@@ -0,0 +1,4 @@
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ADD COLUMN "scoring_mode" character varying(30) NULL DEFAULT 'total_winnings', ADD COLUMN "best_rounds" bigint NULL DEFAULT 0;
+-- Modify "tournament_participants" table
+ALTER TABLE "public"."tournament_participants" ADD COLUMN "score_adjustment" numeric NULL DEFAULT 0;
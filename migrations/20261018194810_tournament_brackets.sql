@@ -0,0 +1,38 @@
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ADD COLUMN "format" character varying(20) NULL DEFAULT 'leaderboard', ADD COLUMN "match_rounds" bigint NULL DEFAULT 3;
+-- Create "tournament_matches" table
+CREATE TABLE "public"."tournament_matches" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "tournament_id" uuid NOT NULL,
+  "round" bigint NOT NULL,
+  "position" bigint NOT NULL,
+  "player_a_id" uuid NULL,
+  "player_b_id" uuid NULL,
+  "seed_a" bigint NULL DEFAULT 0,
+  "seed_b" bigint NULL DEFAULT 0,
+  "wins_a" bigint NULL DEFAULT 0,
+  "wins_b" bigint NULL DEFAULT 0,
+  "winner_id" uuid NULL,
+  "status" character varying(20) NULL DEFAULT 'pending',
+  "completed_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_tournaments_matches" FOREIGN KEY ("tournament_id") REFERENCES "public"."tournaments" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_tournament_match" to table: "tournament_matches"
+CREATE UNIQUE INDEX "idx_tournament_match" ON "public"."tournament_matches" ("tournament_id", "round", "position");
+-- Create "tournament_match_rounds" table
+CREATE TABLE "public"."tournament_match_rounds" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "match_id" uuid NOT NULL,
+  "number" bigint NOT NULL,
+  "roll_a" bigint NOT NULL,
+  "roll_b" bigint NOT NULL,
+  "winner_id" uuid NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_tournament_matches_rounds" FOREIGN KEY ("match_id") REFERENCES "public"."tournament_matches" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_tournament_match_rounds_match_id" to table: "tournament_match_rounds"
+CREATE INDEX "idx_tournament_match_rounds_match_id" ON "public"."tournament_match_rounds" ("match_id");
@@ -0,0 +1,2 @@
+-- Create index "idx_tournament_score" to table: "tournament_participants"
+CREATE INDEX "idx_tournament_score" ON "public"."tournament_participants" ("tournament_id", "score" DESC);
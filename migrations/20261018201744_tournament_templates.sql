@@ -0,0 +1,34 @@
+-- Create "tournament_templates" table
+CREATE TABLE "public"."tournament_templates" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "name" text NOT NULL,
+  "description" text NULL,
+  "recurrence" text NOT NULL,
+  "duration" bigint NOT NULL,
+  "registration_lead" bigint NULL DEFAULT 0,
+  "is_paused" boolean NULL DEFAULT false,
+  "last_starts_at" timestamptz NULL,
+  "game_id" uuid NULL,
+  "format" character varying(20) NULL DEFAULT 'leaderboard',
+  "match_rounds" bigint NULL DEFAULT 3,
+  "scoring_mode" character varying(30) NULL DEFAULT 'total_winnings',
+  "best_rounds" bigint NULL DEFAULT 0,
+  "entry_fee" numeric NULL DEFAULT 0,
+  "prize_pool" numeric NULL DEFAULT 0,
+  "pool_share" numeric NULL DEFAULT 1,
+  "guaranteed_pool" numeric NULL DEFAULT 0,
+  "max_participants" bigint NOT NULL,
+  "min_participants" bigint NULL DEFAULT 0,
+  "prizes" jsonb NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_tournament_templates_game" FOREIGN KEY ("game_id") REFERENCES "public"."games" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+-- Create index "idx_tournament_templates_deleted_at" to table: "tournament_templates"
+CREATE INDEX "idx_tournament_templates_deleted_at" ON "public"."tournament_templates" ("deleted_at");
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ADD COLUMN "template_id" uuid NULL, ADD CONSTRAINT "fk_tournaments_template" FOREIGN KEY ("template_id") REFERENCES "public"."tournament_templates" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+-- Create index "idx_tournaments_template_id" to table: "tournaments"
+CREATE INDEX "idx_tournaments_template_id" ON "public"."tournaments" ("template_id");
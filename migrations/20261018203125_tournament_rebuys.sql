@@ -0,0 +1,8 @@
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ADD COLUMN "late_registration_until" timestamptz NULL, ADD COLUMN "rebuy_limit" bigint NULL DEFAULT 0, ADD COLUMN "rebuy_fee" numeric NULL DEFAULT 0, ADD COLUMN "rebuy_mode" character varying(20) NULL DEFAULT 'reset', ADD COLUMN "rebuy_score" numeric NULL DEFAULT 0, ADD COLUMN "add_on_fee" numeric NULL DEFAULT 0, ADD COLUMN "add_on_score" numeric NULL DEFAULT 0;
+-- Modify "tournament_participants" table
+ALTER TABLE "public"."tournament_participants" ADD COLUMN "scoring_from" timestamptz NULL, ADD COLUMN "rebuys" bigint NULL DEFAULT 0, ADD COLUMN "add_on" boolean NULL DEFAULT false, ADD COLUMN "total_paid" numeric NULL DEFAULT 0;
+-- Modify "tournament_templates" table
+ALTER TABLE "public"."tournament_templates" ADD COLUMN "late_registration" bigint NULL DEFAULT 0, ADD COLUMN "rebuy_limit" bigint NULL DEFAULT 0, ADD COLUMN "rebuy_fee" numeric NULL DEFAULT 0, ADD COLUMN "rebuy_mode" character varying(20) NULL DEFAULT 'reset', ADD COLUMN "rebuy_score" numeric NULL DEFAULT 0, ADD COLUMN "add_on_fee" numeric NULL DEFAULT 0, ADD COLUMN "add_on_score" numeric NULL DEFAULT 0;
+-- Existing entries paid the entry fee
+UPDATE "public"."tournament_participants" AS p SET "total_paid" = t."entry_fee" FROM "public"."tournaments" AS t WHERE t."id" = p."tournament_id";
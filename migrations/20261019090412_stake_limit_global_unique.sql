@@ -0,0 +1,6 @@
+-- Drop duplicate global limits, keeping the most recently updated
+DELETE FROM "public"."stake_limits" AS a USING "public"."stake_limits" AS b
+WHERE a."game_id" IS NULL AND b."game_id" IS NULL AND a."user_id" = b."user_id"
+  AND (a."updated_at", a."id") < (b."updated_at", b."id");
+-- Create index "idx_stake_limit_user_global" to table: "stake_limits"
+CREATE UNIQUE INDEX "idx_stake_limit_user_global" ON "public"."stake_limits" ("user_id") WHERE (game_id IS NULL);
@@ -0,0 +1,4 @@
+-- Modify "bet_legs" table
+ALTER TABLE "public"."bet_legs" ADD COLUMN "refund_fraction" numeric NOT NULL DEFAULT 0;
+-- Modify "event_outcomes" table
+ALTER TABLE "public"."event_outcomes" ADD COLUMN "refund_fraction" numeric NULL;
@@ -0,0 +1,8 @@
+-- Drop index "idx_event_markets_external_id" from table: "event_markets"
+DROP INDEX "public"."idx_event_markets_external_id";
+-- Create index "idx_event_markets_event_external" to table: "event_markets"
+CREATE UNIQUE INDEX "idx_event_markets_event_external" ON "public"."event_markets" ("event_id", "external_id");
+-- Drop index "idx_event_outcomes_external_id" from table: "event_outcomes"
+DROP INDEX "public"."idx_event_outcomes_external_id";
+-- Create index "idx_event_outcomes_market_external" to table: "event_outcomes"
+CREATE UNIQUE INDEX "idx_event_outcomes_market_external" ON "public"."event_outcomes" ("market_id", "external_id");
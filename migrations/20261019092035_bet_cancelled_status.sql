@@ -0,0 +1,6 @@
+-- Mark bets the bettor cancelled, previously stored as refunded
+UPDATE "public"."bets" SET "status" = 'cancelled'
+WHERE "status" = 'refunded' AND "id" IN (
+  SELECT "reference_id" FROM "public"."transactions"
+  WHERE "type" = 'refund' AND "description" LIKE 'Bet cancelled: %'
+);
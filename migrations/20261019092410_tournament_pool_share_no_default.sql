@@ -0,0 +1,4 @@
+-- Modify "tournaments" table
+ALTER TABLE "public"."tournaments" ALTER COLUMN "pool_share" DROP DEFAULT, ALTER COLUMN "pool_share" SET NOT NULL;
+-- Modify "tournament_templates" table
+ALTER TABLE "public"."tournament_templates" ALTER COLUMN "pool_share" DROP DEFAULT, ALTER COLUMN "pool_share" SET NOT NULL;
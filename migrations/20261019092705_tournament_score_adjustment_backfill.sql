@@ -0,0 +1,17 @@
+-- Carry manual points over into "score_adjustment": before scoring modes,
+-- score was the sum of game payouts in the window plus any points an admin
+-- added, so whatever the bets don't explain was added by hand
+UPDATE "public"."tournament_participants" AS p
+SET "score_adjustment" = p."score" - COALESCE((
+  SELECT SUM(b."payout") FROM "public"."bets" AS b
+  WHERE b."user_id" = p."user_id" AND b."game_id" = t."game_id" AND b."type" = 'game'
+    AND b."deleted_at" IS NULL
+    AND b."created_at" BETWEEN GREATEST(t."starts_at", COALESCE(p."scoring_from", t."starts_at")) AND t."ends_at"
+), 0)
+FROM "public"."tournaments" AS t
+WHERE t."id" = p."tournament_id"
+  AND t."status" IN ('draft', 'open', 'in_progress')
+  AND t."format" = 'leaderboard'
+  AND t."scoring_mode" = 'total_winnings'
+  AND t."game_id" IS NOT NULL
+  AND p."score_adjustment" = 0;
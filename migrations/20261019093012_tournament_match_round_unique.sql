@@ -0,0 +1,11 @@
+-- Renumber rounds duplicated by concurrent plays so numbers are unique per match
+UPDATE "public"."tournament_match_rounds" AS r SET "number" = n."rn"
+FROM (
+  SELECT "id", ROW_NUMBER() OVER (PARTITION BY "match_id" ORDER BY "number", "created_at", "id") AS "rn"
+  FROM "public"."tournament_match_rounds"
+) AS n
+WHERE r."id" = n."id" AND r."number" <> n."rn";
+-- Drop index "idx_tournament_match_rounds_match_id" from table: "tournament_match_rounds"
+DROP INDEX "public"."idx_tournament_match_rounds_match_id";
+-- Create index "idx_match_round_number" to table: "tournament_match_rounds"
+CREATE UNIQUE INDEX "idx_match_round_number" ON "public"."tournament_match_rounds" ("match_id", "number");
@@ -0,0 +1,9 @@
+-- Detach duplicate spawns from their template, keeping the first one created
+UPDATE "public"."tournaments" AS a SET "template_id" = NULL
+FROM "public"."tournaments" AS b
+WHERE a."template_id" = b."template_id" AND a."starts_at" = b."starts_at"
+  AND (a."created_at", a."id") > (b."created_at", b."id");
+-- Drop index "idx_tournaments_template_id" from table: "tournaments"
+DROP INDEX "public"."idx_tournaments_template_id";
+-- Create index "idx_tournament_template_start" to table: "tournaments"
+CREATE UNIQUE INDEX "idx_tournament_template_start" ON "public"."tournaments" ("template_id", "starts_at");
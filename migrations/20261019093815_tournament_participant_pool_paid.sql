@@ -0,0 +1,4 @@
+-- Modify "tournament_participants" table
+ALTER TABLE "public"."tournament_participants" ADD COLUMN "pool_paid" numeric NULL DEFAULT 0;
+-- Backfill with the current pool share, the best estimate for existing entries
+UPDATE "public"."tournament_participants" AS p SET "pool_paid" = p."total_paid" * t."pool_share" FROM "public"."tournaments" AS t WHERE t."id" = p."tournament_id";
@@ -26,36 +26,116 @@ const (
 	EventCategoryOther         EventCategory = "other"
 )
 
+// EventMode decides how an event's bets are priced
+type EventMode string
+
+const (
+	EventModeFixed EventMode = "fixed" // fixed odds taken from EventOutcome.Odds
+	EventModePool  EventMode = "pool"  // pari-mutuel, winners share the pool
+)
+
+type MarketType string
+
+const (
+	MarketTypeWinner    MarketType = "winner"
+	MarketType1X2       MarketType = "1x2"
+	MarketTypeOverUnder MarketType = "over_under"
+	MarketTypeHandicap  MarketType = "handicap"
+	MarketTypeYesNo     MarketType = "yes_no"
+)
+
+type MarketStatus string
+
+const (
+	MarketStatusOpen      MarketStatus = "open"
+	MarketStatusSuspended MarketStatus = "suspended"
+	MarketStatusSettled   MarketStatus = "settled"
+	MarketStatusVoid      MarketStatus = "void"
+)
+
 type Event struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Category    EventCategory  `json:"category" gorm:"type:varchar(30);not null"`
-	Status      EventStatus    `json:"status" gorm:"type:varchar(20);default:'upcoming'"`
-	StartsAt    time.Time      `json:"starts_at" gorm:"not null"`
-	EndsAt      *time.Time     `json:"ends_at,omitempty"`
-	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ExternalID     *string        `json:"external_id,omitempty" gorm:"type:varchar(100);uniqueIndex"` // id in an imported feed
+	Name           string         `json:"name" gorm:"not null"`
+	Description    string         `json:"description" gorm:"type:text"`
+	Category       EventCategory  `json:"category" gorm:"type:varchar(30);not null"`
+	Status         EventStatus    `json:"status" gorm:"type:varchar(20);default:'upcoming'"`
+	StartsAt       time.Time      `json:"starts_at" gorm:"not null"`
+	EndsAt         *time.Time     `json:"ends_at,omitempty"`
+	NoInPlay       bool           `json:"no_in_play" gorm:"default:false"`     // betting closes before StartsAt
+	ResultOverdue  bool           `json:"result_overdue" gorm:"default:false"` // past EndsAt without a result
+	Mode           EventMode      `json:"mode" gorm:"type:varchar(20);default:'fixed'"`
+	PoolCommission float64        `json:"pool_commission" gorm:"default:0"` // house cut of pool stakes, e.g. 0.15
+	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
+	Markets  []EventMarket  `json:"markets,omitempty" gorm:"foreignKey:EventID"`
 	Outcomes []EventOutcome `json:"outcomes,omitempty" gorm:"foreignKey:EventID"`
 }
 
+// EventMarket is a single question on an event, e.g. match winner or total goals over 2.5
+type EventMarket struct {
+	ID         uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EventID    uuid.UUID      `json:"event_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_event_markets_event_external"`
+	ExternalID *string        `json:"external_id,omitempty" gorm:"type:varchar(100);uniqueIndex:idx_event_markets_event_external"` // unique within the event
+	Name       string         `json:"name" gorm:"not null"`
+	Type       MarketType     `json:"type" gorm:"type:varchar(20);not null"`
+	Line       *float64       `json:"line,omitempty"` // for over/under and handicap markets
+	Status     MarketStatus   `json:"status" gorm:"type:varchar(20);default:'open'"`
+	Pool       *float64       `json:"pool,omitempty" gorm:"-"` // total stakes, pool events only
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Outcomes []EventOutcome `json:"outcomes,omitempty" gorm:"foreignKey:MarketID"`
+}
+
 type EventOutcome struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	EventID   uuid.UUID      `json:"event_id" gorm:"type:uuid;not null;index"`
-	Name      string         `json:"name" gorm:"not null"`
-	Odds      float64        `json:"odds" gorm:"not null"`
-	IsWinner  *bool          `json:"is_winner,omitempty"`
-	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EventID        uuid.UUID      `json:"event_id" gorm:"type:uuid;not null;index"`
+	MarketID       uuid.UUID      `json:"market_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_event_outcomes_market_external"`
+	ExternalID     *string        `json:"external_id,omitempty" gorm:"type:varchar(100);uniqueIndex:idx_event_outcomes_market_external"` // unique within the market
+	Name           string         `json:"name" gorm:"not null"`
+	Odds           float64        `json:"odds" gorm:"not null"`
+	OddsVersion    int            `json:"odds_version" gorm:"not null;default:1"`
+	IsWinner       *bool          `json:"is_winner,omitempty"`
+	IsVoid         bool           `json:"is_void" gorm:"default:false"`
+	IsSuspended    bool           `json:"is_suspended" gorm:"default:false"`
+	OddsPinned     bool           `json:"odds_pinned" gorm:"default:false"`
+	PoolStake      *float64       `json:"pool_stake,omitempty" gorm:"-"` // pool events only
+	Dividend       *float64       `json:"dividend,omitempty" gorm:"-"`   // indicative pool dividend per unit staked
+	DeadHeatFactor *float64       `json:"dead_heat_factor,omitempty"`    // payout reduction for tied winners
+	RefundFraction *float64       `json:"refund_fraction,omitempty"`     // share of the stake returned on a partial result
+	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// OddsHistory records every odds value an outcome has been offered at
+type OddsHistory struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	OutcomeID uuid.UUID `json:"outcome_id" gorm:"type:uuid;not null;uniqueIndex:idx_odds_history_version"`
+	EventID   uuid.UUID `json:"event_id" gorm:"type:uuid;not null;index"`
+	Version   int       `json:"version" gorm:"not null;uniqueIndex:idx_odds_history_version"`
+	Odds      float64   `json:"odds" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	Outcome EventOutcome `json:"-" gorm:"foreignKey:OutcomeID;constraint:OnDelete:CASCADE"`
 }
 
 func (Event) TableName() string {
 	return "events"
 }
 
+func (EventMarket) TableName() string {
+	return "event_markets"
+}
+
 func (EventOutcome) TableName() string {
 	return "event_outcomes"
 }
+
+func (OddsHistory) TableName() string {
+	return "odds_history"
+}
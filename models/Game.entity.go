@@ -24,17 +24,18 @@ const (
 )
 
 type Game struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Category    GameCategory   `json:"category" gorm:"type:varchar(30);not null"`
-	Status      GameStatus     `json:"status" gorm:"type:varchar(20);default:'active'"`
-	MinBet      float64        `json:"min_bet" gorm:"not null"`
-	MaxBet      float64        `json:"max_bet" gorm:"not null"`
-	HouseEdge   float64        `json:"house_edge" gorm:"default:0"`
-	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name             string         `json:"name" gorm:"not null"`
+	Description      string         `json:"description" gorm:"type:text"`
+	Category         GameCategory   `json:"category" gorm:"type:varchar(30);not null"`
+	Status           GameStatus     `json:"status" gorm:"type:varchar(20);default:'active'"`
+	MinBet           float64        `json:"min_bet" gorm:"not null"`
+	MaxBet           float64        `json:"max_bet" gorm:"not null"`
+	HouseEdge        float64        `json:"house_edge" gorm:"default:0"`         // percent of stakes the house expects to keep, e.g. 2.5
+	DailyExposureCap float64        `json:"daily_exposure_cap" gorm:"default:0"` // max house net loss per day, 0 = no cap
+	CreatedAt        time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 func (Game) TableName() string {
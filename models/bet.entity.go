@@ -10,40 +10,77 @@ import (
 type BetStatus string
 
 const (
-	BetStatusPending  BetStatus = "pending"
-	BetStatusWon      BetStatus = "won"
-	BetStatusLost     BetStatus = "lost"
-	BetStatusRefunded BetStatus = "refunded"
+	BetStatusPending   BetStatus = "pending"
+	BetStatusWon       BetStatus = "won"
+	BetStatusLost      BetStatus = "lost"
+	BetStatusRefunded  BetStatus = "refunded"
+	BetStatusCashedOut BetStatus = "cashed_out"
+	BetStatusCancelled BetStatus = "cancelled" // withdrawn by the bettor, settlement never touches it
 )
 
 type BetType string
 
 const (
-	BetTypeGame  BetType = "game"
-	BetTypeEvent BetType = "event"
+	BetTypeGame        BetType = "game"
+	BetTypeEvent       BetType = "event"
+	BetTypeAccumulator BetType = "accumulator" // multiple event legs, see BetLeg
+)
+
+type BetLegStatus string
+
+const (
+	BetLegStatusPending BetLegStatus = "pending"
+	BetLegStatusWon     BetLegStatus = "won"
+	BetLegStatusLost    BetLegStatus = "lost"
+	BetLegStatusVoid    BetLegStatus = "void"
+	BetLegStatusPartial BetLegStatus = "partial" // part won or refunded, e.g. an Asian quarter line
 )
 
 type Bet struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	UserID    uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
-	Type      BetType        `json:"type" gorm:"type:varchar(20);not null"`
-	GameID    *uuid.UUID     `json:"game_id,omitempty" gorm:"type:uuid;index"`
-	EventID   *uuid.UUID     `json:"event_id,omitempty" gorm:"type:uuid;index"`
-	OutcomeID *uuid.UUID     `json:"outcome_id,omitempty" gorm:"type:uuid;index"`
-	Amount    float64        `json:"amount" gorm:"not null"`
-	Odds      float64        `json:"odds" gorm:"not null"`
-	Status    BetStatus      `json:"status" gorm:"type:varchar(20);default:'pending'"`
-	Payout    float64        `json:"payout" gorm:"default:0"`
-	SettledAt *time.Time     `json:"settled_at,omitempty"`
-	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID      uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type        BetType        `json:"type" gorm:"type:varchar(20);not null"`
+	GameID      *uuid.UUID     `json:"game_id,omitempty" gorm:"type:uuid;index"`
+	EventID     *uuid.UUID     `json:"event_id,omitempty" gorm:"type:uuid;index"`
+	OutcomeID   *uuid.UUID     `json:"outcome_id,omitempty" gorm:"type:uuid;index"`
+	Amount      float64        `json:"amount" gorm:"not null"`
+	Odds        float64        `json:"odds" gorm:"not null"`
+	OddsVersion int            `json:"odds_version,omitempty" gorm:"default:0"` // outcome odds version the bet was accepted at
+	Status      BetStatus      `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	Payout      float64        `json:"payout" gorm:"default:0"`
+	SettledAt   *time.Time     `json:"settled_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
 	//relationships
 	User    User          `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Game    *Game         `json:"-" gorm:"foreignKey:GameID;constraint:OnDelete:SET NULL"`
 	Event   *Event        `json:"-" gorm:"foreignKey:EventID;constraint:OnDelete:SET NULL"`
 	Outcome *EventOutcome `json:"-" gorm:"foreignKey:OutcomeID;constraint:OnDelete:SET NULL"`
+	Legs    []BetLeg      `json:"legs,omitempty" gorm:"foreignKey:BetID"`
+}
+
+// BetLeg is a single selection within an accumulator bet
+type BetLeg struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	BetID          uuid.UUID    `json:"bet_id" gorm:"type:uuid;not null;index"`
+	EventID        uuid.UUID    `json:"event_id" gorm:"type:uuid;not null;index"`
+	OutcomeID      uuid.UUID    `json:"outcome_id" gorm:"type:uuid;not null"`
+	Odds           float64      `json:"odds" gorm:"not null"`
+	OddsVersion    int          `json:"odds_version" gorm:"not null;default:1"`
+	Status         BetLegStatus `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	DeadHeatFactor float64      `json:"dead_heat_factor" gorm:"not null;default:1"`
+	RefundFraction float64      `json:"refund_fraction" gorm:"not null;default:0"`
+	SettledAt      *time.Time   `json:"settled_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+
+	//relationships
+	Bet     Bet          `json:"-" gorm:"foreignKey:BetID;constraint:OnDelete:CASCADE"`
+	Event   Event        `json:"-" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+	Outcome EventOutcome `json:"-" gorm:"foreignKey:OutcomeID;constraint:OnDelete:CASCADE"`
 }
 
-func (Bet) TableName() string { return "bets" }
+func (Bet) TableName() string    { return "bets" }
+func (BetLeg) TableName() string { return "bet_legs" }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventFavourite is an event a user has starred
+type EventFavourite struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	EventID   uuid.UUID `json:"event_id" gorm:"type:uuid;primaryKey;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	//relationships
+	User  User  `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Event Event `json:"event,omitempty" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+}
+
+// EventSubscription sends a user WebSocket notifications about an event
+// whether or not they are viewing it
+type EventSubscription struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	EventID   uuid.UUID `json:"event_id" gorm:"type:uuid;primaryKey;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	//relationships
+	User  User  `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Event Event `json:"event,omitempty" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+}
+
+func (EventFavourite) TableName() string    { return "event_favourites" }
+func (EventSubscription) TableName() string { return "event_subscriptions" }
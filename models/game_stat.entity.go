@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GamePlayerDailyStat is a rollup of one player's game bets on one UTC day.
+// It is rebuilt from bets by the analytics service and backs admin reporting.
+type GamePlayerDailyStat struct {
+	GameID    uuid.UUID `json:"game_id" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	Day       time.Time `json:"day" gorm:"type:date;primaryKey"`
+	Rounds    int64     `json:"rounds" gorm:"not null;default:0"`
+	Handle    float64   `json:"handle" gorm:"not null;default:0"`
+	Payouts   float64   `json:"payouts" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	//relationships
+	Game Game `json:"-" gorm:"foreignKey:GameID;constraint:OnDelete:CASCADE"`
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (GamePlayerDailyStat) TableName() string { return "game_player_daily_stats" }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SettlementAction string
+
+const (
+	SettlementActionSettle   SettlementAction = "settle"
+	SettlementActionRollback SettlementAction = "rollback"
+	SettlementActionResettle SettlementAction = "resettle"
+)
+
+// SettlementAudit records who settled or unsettled an event, and with which results
+type SettlementAudit struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EventID   uuid.UUID        `json:"event_id" gorm:"type:uuid;not null;index"`
+	AdminID   uuid.UUID        `json:"admin_id" gorm:"type:uuid;not null"`
+	Action    SettlementAction `json:"action" gorm:"type:varchar(20);not null"`
+	Reason    string           `json:"reason" gorm:"type:text"`
+	Details   string           `json:"details" gorm:"type:text"` // JSON encoded request
+	CreatedAt time.Time        `json:"created_at" gorm:"autoCreateTime"`
+
+	//relationships
+	Event Event `json:"-" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+	Admin User  `json:"-" gorm:"foreignKey:AdminID;constraint:OnDelete:NO ACTION"`
+}
+
+func (SettlementAudit) TableName() string { return "settlement_audits" }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StakeLimit is an admin-set cap on a single stake for a user.
+// A nil GameID applies the limit to every game.
+type StakeLimit struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_stake_limit_user_game;uniqueIndex:idx_stake_limit_user_global,where:game_id IS NULL"`
+	GameID    *uuid.UUID `json:"game_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_stake_limit_user_game"`
+	MaxStake  float64    `json:"max_stake" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	//relationships
+	User User  `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Game *Game `json:"-" gorm:"foreignKey:GameID;constraint:OnDelete:CASCADE"`
+}
+
+func (StakeLimit) TableName() string { return "stake_limits" }
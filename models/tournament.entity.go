@@ -17,39 +17,216 @@ const (
 	TournamentStatusCancelled  TournamentStatus = "cancelled"
 )
 
+type PrizeType string
+
+const (
+	PrizeTypePercentage PrizeType = "percentage" // share of the prize pool per rank
+	PrizeTypeFixed      PrizeType = "fixed"      // cash amount per rank
+	PrizeTypeFreeSpins  PrizeType = "free_spins" // non-cash, Amount spins per rank
+)
+
+// TournamentFormat decides how a tournament is played
+type TournamentFormat string
+
+const (
+	TournamentFormatLeaderboard TournamentFormat = "leaderboard" // ranked by score from bets
+	TournamentFormatKnockout    TournamentFormat = "knockout"    // seeded bracket of head-to-head matches
+)
+
+type MatchStatus string
+
+const (
+	MatchStatusPending   MatchStatus = "pending"   // waiting for players from earlier rounds
+	MatchStatusReady     MatchStatus = "ready"     // both players known, rounds can be played
+	MatchStatusCompleted MatchStatus = "completed" // winner decided, or a bye
+)
+
+// RebuyMode decides what a rebuy does to the participant's score
+type RebuyMode string
+
+const (
+	RebuyModeReset RebuyMode = "reset"  // score starts again from zero
+	RebuyModeTopUp RebuyMode = "top_up" // RebuyScore points are added
+)
+
+// ScoringMode decides how bets in the tournament window turn into a score
+type ScoringMode string
+
+const (
+	ScoringModeTotalWinnings     ScoringMode = "total_winnings"     // sum of payouts
+	ScoringModeBiggestMultiplier ScoringMode = "biggest_multiplier" // best payout / stake
+	ScoringModeNetProfit         ScoringMode = "net_profit"         // payouts less stakes
+	ScoringModeTotalWagered      ScoringMode = "total_wagered"      // sum of stakes
+	ScoringModeWins              ScoringMode = "wins"               // number of winning rounds
+	ScoringModeBestRounds        ScoringMode = "best_rounds"        // profit of the best BestRounds rounds
+)
+
 type Tournament struct {
-	ID              uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name            string           `json:"name" gorm:"not null"`
-	Description     string           `json:"description" gorm:"type:text"`
-	Status          TournamentStatus `json:"status" gorm:"type:varchar(20);default:'draft'"`
-	GameID          *uuid.UUID       `json:"game_id,omitempty" gorm:"type:uuid;index"`
-	EntryFee        float64          `json:"entry_fee" gorm:"default:0"`
-	PrizePool       float64          `json:"prize_pool" gorm:"default:0"`
-	MaxParticipants int              `json:"max_participants" gorm:"not null"`
-	StartsAt        time.Time        `json:"starts_at" gorm:"not null"`
-	EndsAt          time.Time        `json:"ends_at" gorm:"not null"`
-	CreatedAt       time.Time        `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt       time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt       gorm.DeletedAt   `json:"-" gorm:"index"`
+	ID                    uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name                  string           `json:"name" gorm:"not null"`
+	Description           string           `json:"description" gorm:"type:text"`
+	Status                TournamentStatus `json:"status" gorm:"type:varchar(20);default:'draft'"`
+	GameID                *uuid.UUID       `json:"game_id,omitempty" gorm:"type:uuid;index"`
+	TemplateID            *uuid.UUID       `json:"template_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_tournament_template_start"` // recurring template it was spawned from
+	Format                TournamentFormat `json:"format" gorm:"type:varchar(20);default:'leaderboard'"`
+	MatchRounds           int              `json:"match_rounds,omitempty" gorm:"default:3"` // knockout matches are best of MatchRounds dice rounds
+	ScoringMode           ScoringMode      `json:"scoring_mode" gorm:"type:varchar(30);default:'total_winnings'"`
+	BestRounds            int              `json:"best_rounds,omitempty" gorm:"default:0"` // N for best_rounds scoring
+	EntryFee              float64          `json:"entry_fee" gorm:"default:0"`
+	PrizePool             float64          `json:"prize_pool" gorm:"default:0"`      // seed plus entry fee contributions
+	PoolShare             float64          `json:"pool_share" gorm:"not null"`       // fraction of each entry fee added to the pool, the rest is rake
+	GuaranteedPool        float64          `json:"guaranteed_pool" gorm:"default:0"` // minimum paid out, the house covers any shortfall
+	Overlay               float64          `json:"overlay" gorm:"default:0"`         // house top-up paid at the end
+	CurrentPool           float64          `json:"current_pool" gorm:"-"`            // what would be paid out now
+	MaxParticipants       int              `json:"max_participants" gorm:"not null"`
+	MinParticipants       int              `json:"min_participants" gorm:"default:0"`    // cancelled at StartsAt if not reached
+	RegistrationAt        *time.Time       `json:"registration_at,omitempty"`            // when a draft opens for registration
+	LateRegistrationUntil *time.Time       `json:"late_registration_until,omitempty"`    // joining stays open into in_progress until then
+	RebuyLimit            int              `json:"rebuy_limit" gorm:"default:0"`         // rebuys per participant, 0 = none
+	RebuyFee              float64          `json:"rebuy_fee,omitempty" gorm:"default:0"` // 0 = the entry fee
+	RebuyMode             RebuyMode        `json:"rebuy_mode,omitempty" gorm:"type:varchar(20);default:'reset'"`
+	RebuyScore            float64          `json:"rebuy_score,omitempty" gorm:"default:0"` // points a top_up rebuy adds
+	AddOnFee              float64          `json:"add_on_fee,omitempty" gorm:"default:0"`  // one add-on per participant, 0 = none
+	AddOnScore            float64          `json:"add_on_score,omitempty" gorm:"default:0"`
+	StartsAt              time.Time        `json:"starts_at" gorm:"not null;uniqueIndex:idx_tournament_template_start"`
+	EndsAt                time.Time        `json:"ends_at" gorm:"not null"`
+	CreatedAt             time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt             time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt             gorm.DeletedAt   `json:"-" gorm:"index"`
 
 	//relationships
 	Game         *Game                   `json:"-" gorm:"foreignKey:GameID;constraint:OnDelete:SET NULL"`
+	Template     *TournamentTemplate     `json:"-" gorm:"foreignKey:TemplateID;constraint:OnDelete:SET NULL"`
 	Participants []TournamentParticipant `json:"participants,omitempty" gorm:"foreignKey:TournamentID"`
+	Prizes       []TournamentPrize       `json:"prizes,omitempty" gorm:"foreignKey:TournamentID"`
+	Matches      []TournamentMatch       `json:"matches,omitempty" gorm:"foreignKey:TournamentID"`
 }
 
-type TournamentParticipant struct {
+// TournamentPrize awards every rank from RankFrom to RankTo inclusive
+type TournamentPrize struct {
 	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	TournamentID uuid.UUID `json:"tournament_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_user"`
-	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_user"`
-	Score        float64   `json:"score" gorm:"default:0"`
-	Rank         int       `json:"rank" gorm:"default:0"`
-	PrizeWon     float64   `json:"prize_won" gorm:"default:0"`
-	JoinedAt     time.Time `json:"joined_at" gorm:"autoCreateTime"`
+	TournamentID uuid.UUID `json:"tournament_id" gorm:"type:uuid;not null;index"`
+	RankFrom     int       `json:"rank_from" gorm:"not null"`
+	RankTo       int       `json:"rank_to" gorm:"not null"`
+	Type         PrizeType `json:"type" gorm:"type:varchar(20);not null"`
+	Amount       float64   `json:"amount" gorm:"not null"`
+	Description  string    `json:"description,omitempty"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	//relationships
+	Tournament Tournament `json:"-" gorm:"foreignKey:TournamentID;constraint:OnDelete:CASCADE"`
+}
+
+type TournamentParticipant struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TournamentID    uuid.UUID  `json:"tournament_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_user;index:idx_tournament_score,priority:1"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_user"`
+	Score           float64    `json:"score" gorm:"default:0;index:idx_tournament_score,priority:2,sort:desc"`
+	ScoreAdjustment float64    `json:"score_adjustment" gorm:"default:0"` // manual, rebuy and add-on points on top of the computed score
+	ScoringFrom     *time.Time `json:"scoring_from,omitempty"`            // bets before this don't count, set by late joins and reset rebuys
+	Rebuys          int        `json:"rebuys" gorm:"default:0"`
+	AddOn           bool       `json:"add_on" gorm:"default:false"`
+	TotalPaid       float64    `json:"total_paid" gorm:"default:0"` // entry fee, rebuys and add-on
+	PoolPaid        float64    `json:"-" gorm:"default:0"`          // share of TotalPaid that went into the prize pool
+	Rank            int        `json:"rank" gorm:"default:0"`
+	PrizeWon        float64    `json:"prize_won" gorm:"default:0"`
+	Award           string     `json:"award,omitempty"` // non-cash prize, e.g. "10 free spins"
+	JoinedAt        time.Time  `json:"joined_at" gorm:"autoCreateTime"`
 
 	//relationships
 	Tournament Tournament `json:"-" gorm:"foreignKey:TournamentID;constraint:OnDelete:CASCADE"`
 	User       User       `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
 
+// TournamentMatch is a head-to-head knockout match. Round 1 is the first
+// round and the winner of Position p moves on to Position p/2 of the next.
+type TournamentMatch struct {
+	ID           uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TournamentID uuid.UUID   `json:"tournament_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_match"`
+	Round        int         `json:"round" gorm:"not null;uniqueIndex:idx_tournament_match"`
+	Position     int         `json:"position" gorm:"not null;uniqueIndex:idx_tournament_match"`
+	PlayerAID    *uuid.UUID  `json:"player_a_id,omitempty" gorm:"type:uuid"`
+	PlayerBID    *uuid.UUID  `json:"player_b_id,omitempty" gorm:"type:uuid"`
+	SeedA        int         `json:"seed_a,omitempty" gorm:"default:0"`
+	SeedB        int         `json:"seed_b,omitempty" gorm:"default:0"`
+	WinsA        int         `json:"wins_a" gorm:"default:0"`
+	WinsB        int         `json:"wins_b" gorm:"default:0"`
+	WinnerID     *uuid.UUID  `json:"winner_id,omitempty" gorm:"type:uuid"`
+	Status       MatchStatus `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	CompletedAt  *time.Time  `json:"completed_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+
+	//relationships
+	Tournament Tournament             `json:"-" gorm:"foreignKey:TournamentID;constraint:OnDelete:CASCADE"`
+	Rounds     []TournamentMatchRound `json:"rounds,omitempty" gorm:"foreignKey:MatchID"`
+}
+
+// TournamentMatchRound is one dice round of a match, WinnerID is nil on a tie
+type TournamentMatchRound struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	MatchID   uuid.UUID  `json:"match_id" gorm:"type:uuid;not null;uniqueIndex:idx_match_round_number"`
+	Number    int        `json:"number" gorm:"not null;uniqueIndex:idx_match_round_number"`
+	RollA     int        `json:"roll_a" gorm:"not null"`
+	RollB     int        `json:"roll_b" gorm:"not null"`
+	WinnerID  *uuid.UUID `json:"winner_id,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	//relationships
+	Match TournamentMatch `json:"-" gorm:"foreignKey:MatchID;constraint:OnDelete:CASCADE"`
+}
+
+// TournamentTemplate spawns a tournament for every occurrence of Recurrence,
+// a five field cron expression in UTC, copying its settings into each one.
+// Editing or pausing it leaves tournaments already spawned as they are.
+type TournamentTemplate struct {
+	ID               uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name             string           `json:"name" gorm:"not null"`
+	Description      string           `json:"description" gorm:"type:text"`
+	Recurrence       string           `json:"recurrence" gorm:"not null"`
+	Duration         int              `json:"duration" gorm:"not null"`           // minutes from StartsAt to EndsAt
+	RegistrationLead int              `json:"registration_lead" gorm:"default:0"` // minutes before StartsAt registration opens, 0 = as soon as spawned
+	IsPaused         bool             `json:"is_paused" gorm:"default:false"`
+	LastStartsAt     *time.Time       `json:"last_starts_at,omitempty"` // StartsAt of the latest tournament spawned
+	GameID           *uuid.UUID       `json:"game_id,omitempty" gorm:"type:uuid"`
+	Format           TournamentFormat `json:"format" gorm:"type:varchar(20);default:'leaderboard'"`
+	MatchRounds      int              `json:"match_rounds,omitempty" gorm:"default:3"`
+	ScoringMode      ScoringMode      `json:"scoring_mode" gorm:"type:varchar(30);default:'total_winnings'"`
+	BestRounds       int              `json:"best_rounds,omitempty" gorm:"default:0"`
+	EntryFee         float64          `json:"entry_fee" gorm:"default:0"`
+	PrizePool        float64          `json:"prize_pool" gorm:"default:0"`
+	PoolShare        float64          `json:"pool_share" gorm:"not null"`
+	GuaranteedPool   float64          `json:"guaranteed_pool" gorm:"default:0"`
+	MaxParticipants  int              `json:"max_participants" gorm:"not null"`
+	MinParticipants  int              `json:"min_participants" gorm:"default:0"`
+	LateRegistration int              `json:"late_registration" gorm:"default:0"` // minutes after StartsAt joining stays open
+	RebuyLimit       int              `json:"rebuy_limit" gorm:"default:0"`
+	RebuyFee         float64          `json:"rebuy_fee,omitempty" gorm:"default:0"`
+	RebuyMode        RebuyMode        `json:"rebuy_mode,omitempty" gorm:"type:varchar(20);default:'reset'"`
+	RebuyScore       float64          `json:"rebuy_score,omitempty" gorm:"default:0"`
+	AddOnFee         float64          `json:"add_on_fee,omitempty" gorm:"default:0"`
+	AddOnScore       float64          `json:"add_on_score,omitempty" gorm:"default:0"`
+	Prizes           []TemplatePrize  `json:"prizes" gorm:"type:jsonb;serializer:json"`
+	CreatedAt        time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt   `json:"-" gorm:"index"`
+
+	//relationships
+	Game *Game `json:"-" gorm:"foreignKey:GameID;constraint:OnDelete:SET NULL"`
+}
+
+// TemplatePrize is a prize table row copied into each spawned tournament
+type TemplatePrize struct {
+	RankFrom    int       `json:"rank_from"`
+	RankTo      int       `json:"rank_to"`
+	Type        PrizeType `json:"type"`
+	Amount      float64   `json:"amount"`
+	Description string    `json:"description,omitempty"`
+}
+
 func (Tournament) TableName() string            { return "tournaments" }
 func (TournamentParticipant) TableName() string { return "tournament_participants" }
+func (TournamentPrize) TableName() string       { return "tournament_prizes" }
+func (TournamentMatch) TableName() string       { return "tournament_matches" }
+func (TournamentMatchRound) TableName() string  { return "tournament_match_rounds" }
+func (TournamentTemplate) TableName() string    { return "tournament_templates" }
@@ -17,7 +17,11 @@ const (
 	TransactionTypeRefund          TransactionType = "refund"
 	TransactionTypeTournamentEntry TransactionType = "tournament_entry"
 	TransactionTypeTournamentPrize TransactionType = "tournament_prize"
+	TransactionTypeTournamentRebuy TransactionType = "tournament_rebuy"
+	TransactionTypeTournamentAddOn TransactionType = "tournament_add_on"
 	TransactionTypeTransfer        TransactionType = "transfer"
+	TransactionTypeCashOut         TransactionType = "cash_out"
+	TransactionTypeReversal        TransactionType = "settlement_reversal"
 )
 
 type TransactionStatus string
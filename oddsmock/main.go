@@ -0,0 +1,30 @@
+// Command oddsmock serves a fixtures feed over HTTP with drifting prices so
+// the odds feed can be run against it offline.
+//
+//	go run ./oddsmock -file fixtures.json -addr :8090
+//	ODDS_FEED_URL=http://localhost:8090/feed go run .
+package main
+
+import (
+	"flag"
+	"gamba/event"
+	"log"
+	"net/http"
+)
+
+func main() {
+	file := flag.String("file", "", "feed to serve (.json or .csv)")
+	addr := flag.String("addr", ":8090", "address to listen on")
+	drift := flag.Float64("drift", 0.05, "maximum relative price change per request")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		return
+	}
+
+	http.Handle("/feed", event.MockFeedHandler(*file, *drift))
+
+	log.Printf("Serving %s on %s/feed", *file, *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,299 @@
+package tournament
+
+import (
+	"errors"
+	"log"
+	"math/bits"
+	"math/rand"
+	"time"
+
+	"gamba/chat"
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBracket returns every round of a knockout tournament's bracket
+func (s *Service) GetBracket(tournamentID uuid.UUID) (*Bracket, error) {
+	var tournament models.Tournament
+	if err := s.db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTournamentNotFound
+		}
+		return nil, err
+	}
+	if tournament.Format != models.TournamentFormatKnockout {
+		return nil, ErrNotKnockout
+	}
+
+	var matches []models.TournamentMatch
+	if err := s.db.Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+		return db.Order("number ASC")
+	}).Where("tournament_id = ?", tournamentID).
+		Order("round ASC, position ASC").
+		Find(&matches).Error; err != nil {
+		return nil, err
+	}
+
+	bracket := &Bracket{
+		TournamentID: tournament.ID,
+		Status:       tournament.Status,
+		Rounds:       []BracketRound{},
+	}
+	for _, match := range matches {
+		if len(bracket.Rounds) < match.Round {
+			bracket.Rounds = append(bracket.Rounds, BracketRound{Round: match.Round})
+		}
+		round := &bracket.Rounds[match.Round-1]
+		round.Matches = append(round.Matches, match)
+	}
+	if n := len(matches); n > 0 {
+		bracket.ChampionID = matches[n-1].WinnerID
+	}
+	return bracket, nil
+}
+
+// PlayMatch plays the next dice round of a player's knockout match. Both
+// players roll two dice, the higher total takes the round and ties are
+// replayed. The first to win a majority of MatchRounds takes the match.
+func (s *Service) PlayMatch(userID, tournamentID, matchID uuid.UUID) (*MatchRoundResult, error) {
+	var tournament models.Tournament
+	if err := s.db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTournamentNotFound
+		}
+		return nil, err
+	}
+	if tournament.Format != models.TournamentFormatKnockout {
+		return nil, ErrNotKnockout
+	}
+	if tournament.Status != models.TournamentStatusInProgress {
+		return nil, ErrTournamentNotActive
+	}
+
+	var match models.TournamentMatch
+	var round models.TournamentMatchRound
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the match so both players rolling at once play one round each
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tournament_id = ?", matchID, tournamentID).First(&match).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMatchNotFound
+			}
+			return err
+		}
+		if match.Status != models.MatchStatusReady {
+			return ErrMatchNotReady
+		}
+		if *match.PlayerAID != userID && *match.PlayerBID != userID {
+			return ErrNotInMatch
+		}
+
+		var played int64
+		if err := tx.Model(&models.TournamentMatchRound{}).Where("match_id = ?", match.ID).Count(&played).Error; err != nil {
+			return err
+		}
+
+		round = models.TournamentMatchRound{
+			ID:      uuid.New(),
+			MatchID: match.ID,
+			Number:  int(played) + 1,
+			RollA:   rollDice(),
+			RollB:   rollDice(),
+		}
+		column := ""
+		switch {
+		case round.RollA > round.RollB:
+			round.WinnerID = match.PlayerAID
+			match.WinsA++
+			column = "wins_a"
+		case round.RollB > round.RollA:
+			round.WinnerID = match.PlayerBID
+			match.WinsB++
+			column = "wins_b"
+		}
+
+		if err := tx.Create(&round).Error; err != nil {
+			return err
+		}
+		if column != "" {
+			if err := tx.Model(&match).Update(column, gorm.Expr(column+" + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		needed := tournament.MatchRounds/2 + 1
+		switch {
+		case match.WinsA >= needed:
+			return s.completeMatch(tx, &tournament, &match, *match.PlayerAID)
+		case match.WinsB >= needed:
+			return s.completeMatch(tx, &tournament, &match, *match.PlayerBID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MatchRoundResult{Match: match, Round: round}
+	if s.hub != nil {
+		msg := chat.WSMessage{Type: "match_round", Payload: result}
+		s.hub.SendToUser(*match.PlayerAID, msg)
+		s.hub.SendToUser(*match.PlayerBID, msg)
+	}
+	s.publishBracket(tournamentID)
+
+	return result, nil
+}
+
+// seedBracket creates every match of a knockout bracket when the tournament
+// starts. Players are seeded in the order they registered, the bracket is
+// padded to a power of two and the top seeds get byes.
+func (s *Service) seedBracket(tx *gorm.DB, tournament *models.Tournament) error {
+	var participants []models.TournamentParticipant
+	if err := tx.Where("tournament_id = ?", tournament.ID).Order("joined_at ASC").Find(&participants).Error; err != nil {
+		return err
+	}
+	if len(participants) < 2 {
+		return ErrKnockoutTooSmall
+	}
+
+	size := 2
+	for size < len(participants) {
+		size *= 2
+	}
+	rounds := bits.Len(uint(size)) - 1
+
+	var matches []models.TournamentMatch
+	for round := 1; round <= rounds; round++ {
+		for position := 0; position < size>>round; position++ {
+			matches = append(matches, models.TournamentMatch{
+				ID:           uuid.New(),
+				TournamentID: tournament.ID,
+				Round:        round,
+				Position:     position,
+				Status:       models.MatchStatusPending,
+			})
+		}
+	}
+
+	order := seedOrder(size)
+	for position := 0; position < size/2; position++ {
+		match := &matches[position]
+		match.SeedA = order[2*position]
+		match.PlayerAID = &participants[match.SeedA-1].UserID
+		if seed := order[2*position+1]; seed <= len(participants) {
+			match.SeedB = seed
+			match.PlayerBID = &participants[seed-1].UserID
+			match.Status = models.MatchStatusReady
+		}
+	}
+
+	if err := tx.Create(&matches).Error; err != nil {
+		return err
+	}
+
+	// Byes go straight through to the next round
+	for i := range matches[:size/2] {
+		if matches[i].PlayerBID != nil {
+			continue
+		}
+		if err := s.completeMatch(tx, tournament, &matches[i], *matches[i].PlayerAID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completeMatch records the winner and moves them into their next match. A
+// match won is worth a point so the bracket ranks like a leaderboard, and
+// winning the final ends the tournament.
+func (s *Service) completeMatch(tx *gorm.DB, tournament *models.Tournament, match *models.TournamentMatch, winnerID uuid.UUID) error {
+	now := time.Now()
+	match.WinnerID = &winnerID
+	match.Status = models.MatchStatusCompleted
+	match.CompletedAt = &now
+	if err := tx.Model(match).Updates(map[string]interface{}{
+		"winner_id":    winnerID,
+		"status":       match.Status,
+		"completed_at": now,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.TournamentParticipant{}).
+		Where("tournament_id = ? AND user_id = ?", tournament.ID, winnerID).
+		Update("score", gorm.Expr("score + 1")).Error; err != nil {
+		return err
+	}
+
+	var next models.TournamentMatch
+	if err := tx.Where("tournament_id = ? AND round = ? AND position = ?", tournament.ID, match.Round+1, match.Position/2).
+		First(&next).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.endTournament(tx, tournament)
+		}
+		return err
+	}
+
+	seed := match.SeedA
+	if match.PlayerBID != nil && *match.PlayerBID == winnerID {
+		seed = match.SeedB
+	}
+
+	updates := map[string]interface{}{}
+	if match.Position%2 == 0 {
+		updates["player_a_id"] = winnerID
+		updates["seed_a"] = seed
+		if next.PlayerBID != nil {
+			updates["status"] = models.MatchStatusReady
+		}
+	} else {
+		updates["player_b_id"] = winnerID
+		updates["seed_b"] = seed
+		if next.PlayerAID != nil {
+			updates["status"] = models.MatchStatusReady
+		}
+	}
+	return tx.Model(&next).Updates(updates).Error
+}
+
+// publishBracket pushes the current bracket to everyone watching the tournament
+func (s *Service) publishBracket(tournamentID uuid.UUID) {
+	if s.hub == nil {
+		return
+	}
+
+	bracket, err := s.GetBracket(tournamentID)
+	if err != nil {
+		log.Printf("Error loading bracket of tournament %s: %v", tournamentID, err)
+		return
+	}
+	s.hub.Publish(tournamentTopic(tournamentID), chat.WSMessage{
+		Type:    "bracket_updated",
+		Payload: bracket,
+	})
+}
+
+// seedOrder lists seeds in first round bracket order for a bracket of size
+// players, e.g. 1 8 4 5 2 7 3 6, so the top seeds can only meet late on
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order) * 2
+		next := make([]int, 0, n)
+		for _, seed := range order {
+			next = append(next, seed, n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// rollDice totals two six-sided dice
+func rollDice() int {
+	return rand.Intn(6) + rand.Intn(6) + 2
+}
@@ -0,0 +1,100 @@
+package tournament
+
+import (
+	"errors"
+	"log"
+
+	"gamba/chat"
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Cancel cancels a tournament that hasn't paid out yet, refunding everything
+// each participant paid in and notifying them (admin only)
+func (s *Service) Cancel(tournamentID uuid.UUID) (*models.Tournament, error) {
+	var tournament models.Tournament
+	if err := s.db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTournamentNotFound
+		}
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.cancelTournament(tx, &tournament)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyCancelled(&tournament)
+	return &tournament, nil
+}
+
+// cancelTournament refunds every participant's entry fee, rebuys and add-on
+// and marks the tournament cancelled. The status is claimed first so a
+// tournament can't be refunded twice or cancelled once prizes are paid.
+func (s *Service) cancelTournament(tx *gorm.DB, tournament *models.Tournament) error {
+	result := tx.Model(tournament).
+		Where("status IN ?", []models.TournamentStatus{
+			models.TournamentStatusDraft,
+			models.TournamentStatusOpen,
+			models.TournamentStatusInProgress,
+		}).
+		Update("status", models.TournamentStatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCannotCancel
+	}
+
+	var participants []models.TournamentParticipant
+	if err := tx.Where("tournament_id = ?", tournament.ID).Find(&participants).Error; err != nil {
+		return err
+	}
+
+	for _, p := range participants {
+		if p.TotalPaid <= 0 {
+			continue
+		}
+		if err := s.credit(tx, p.UserID, tournament, p.TotalPaid, models.TransactionTypeRefund, "Tournament cancelled: "+tournament.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyCancelled tells each participant what they were refunded and anyone
+// watching the tournament that it was cancelled
+func (s *Service) notifyCancelled(tournament *models.Tournament) {
+	if s.hub == nil {
+		return
+	}
+
+	var participants []models.TournamentParticipant
+	if err := s.db.Where("tournament_id = ?", tournament.ID).Find(&participants).Error; err != nil {
+		log.Printf("Error loading participants of tournament %s: %v", tournament.ID, err)
+		return
+	}
+
+	for _, p := range participants {
+		s.hub.SendToUser(p.UserID, chat.WSMessage{
+			Type: "tournament_cancelled",
+			Payload: TournamentCancelled{
+				TournamentID: tournament.ID,
+				Name:         tournament.Name,
+				Refund:       p.TotalPaid,
+			},
+		})
+	}
+	s.hub.Publish(tournamentTopic(tournament.ID), chat.WSMessage{
+		Type: "tournament_cancelled",
+		Payload: TournamentCancelled{
+			TournamentID: tournament.ID,
+			Name:         tournament.Name,
+		},
+	})
+}
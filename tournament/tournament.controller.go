@@ -20,8 +20,12 @@ func (c *Controller) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/tournaments", c.GetAll)
 	r.GET("/tournaments/:id", c.GetByID)
 	r.GET("/tournaments/:id/leaderboard", c.GetLeaderboard)
+	r.GET("/tournaments/:id/bracket", c.GetBracket)
+	r.POST("/tournaments/:id/matches/:matchId/play", c.PlayMatch)
 	r.POST("/tournaments/:id/join", c.Join)
 	r.POST("/tournaments/:id/leave", c.Leave)
+	r.POST("/tournaments/:id/rebuy", c.Rebuy)
+	r.POST("/tournaments/:id/add-on", c.AddOn)
 }
 
 func (c *Controller) RegisterAdminRoutes(r *gin.RouterGroup) {
@@ -30,6 +34,15 @@ func (c *Controller) RegisterAdminRoutes(r *gin.RouterGroup) {
 	r.DELETE("/tournaments/:id", c.Delete)
 	r.POST("/tournaments/:id/score", c.UpdateScore)
 	r.POST("/tournaments/:id/end", c.EndTournament)
+	r.POST("/tournaments/:id/cancel", c.Cancel)
+
+	r.GET("/tournament-templates", c.GetTemplates)
+	r.GET("/tournament-templates/:id", c.GetTemplate)
+	r.POST("/tournament-templates", c.CreateTemplate)
+	r.PUT("/tournament-templates/:id", c.UpdateTemplate)
+	r.DELETE("/tournament-templates/:id", c.DeleteTemplate)
+	r.POST("/tournament-templates/:id/pause", c.PauseTemplate)
+	r.POST("/tournament-templates/:id/resume", c.ResumeTemplate)
 }
 
 func (c *Controller) GetAll(ctx *gin.Context) {
@@ -76,7 +89,7 @@ func (c *Controller) Create(ctx *gin.Context) {
 
 	tournament, err := c.service.Create(&req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		handleError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusCreated, tournament)
@@ -153,6 +166,40 @@ func (c *Controller) Leave(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "left tournament"})
 }
 
+func (c *Controller) Rebuy(ctx *gin.Context) {
+	user := auth.GetClaims(ctx)
+
+	tournamentID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	participant, err := c.service.Rebuy(user.UserID, tournamentID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, participant)
+}
+
+func (c *Controller) AddOn(ctx *gin.Context) {
+	user := auth.GetClaims(ctx)
+
+	tournamentID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	participant, err := c.service.AddOn(user.UserID, tournamentID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, participant)
+}
+
 func (c *Controller) UpdateScore(ctx *gin.Context) {
 
 	tournamentID, err := uuid.Parse(ctx.Param("id"))
@@ -181,7 +228,13 @@ func (c *Controller) GetLeaderboard(ctx *gin.Context) {
 		return
 	}
 
-	leaderboard, err := c.service.GetLeaderboard(tournamentID)
+	var query LeaderboardQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+
+	leaderboard, err := c.service.GetLeaderboard(tournamentID, auth.GetClaims(ctx).UserID, &query)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -189,6 +242,43 @@ func (c *Controller) GetLeaderboard(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, leaderboard)
 }
 
+func (c *Controller) GetBracket(ctx *gin.Context) {
+	tournamentID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	bracket, err := c.service.GetBracket(tournamentID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, bracket)
+}
+
+func (c *Controller) PlayMatch(ctx *gin.Context) {
+	user := auth.GetClaims(ctx)
+
+	tournamentID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	matchID, err := uuid.Parse(ctx.Param("matchId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	result, err := c.service.PlayMatch(user.UserID, tournamentID, matchID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
 func (c *Controller) EndTournament(ctx *gin.Context) {
 
 	tournamentID, err := uuid.Parse(ctx.Param("id"))
@@ -204,15 +294,127 @@ func (c *Controller) EndTournament(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "tournament ended"})
 }
 
+func (c *Controller) Cancel(ctx *gin.Context) {
+	tournamentID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	tournament, err := c.service.Cancel(tournamentID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tournament)
+}
+
+func (c *Controller) GetTemplates(ctx *gin.Context) {
+	templates, err := c.service.GetTemplates()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, templates)
+}
+
+func (c *Controller) GetTemplate(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	template, err := c.service.GetTemplate(id)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, template)
+}
+
+func (c *Controller) CreateTemplate(ctx *gin.Context) {
+	var req TemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	template, err := c.service.CreateTemplate(&req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, template)
+}
+
+func (c *Controller) UpdateTemplate(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req UpdateTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	template, err := c.service.UpdateTemplate(id, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, template)
+}
+
+func (c *Controller) DeleteTemplate(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := c.service.DeleteTemplate(id); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+func (c *Controller) PauseTemplate(ctx *gin.Context) {
+	c.setTemplatePaused(ctx, true)
+}
+
+func (c *Controller) ResumeTemplate(ctx *gin.Context) {
+	c.setTemplatePaused(ctx, false)
+}
+
+func (c *Controller) setTemplatePaused(ctx *gin.Context, paused bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	template, err := c.service.SetTemplatePaused(id, paused)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, template)
+}
+
 func handleError(ctx *gin.Context, err error) {
 	switch err {
-	case ErrTournamentNotFound:
+	case ErrTournamentNotFound, ErrMatchNotFound, ErrTemplateNotFound:
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 	case ErrTournamentFull:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case ErrTournamentNotOpen:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	case ErrAlreadyJoined:
+	case ErrAlreadyJoined, ErrRebuyLimitReached, ErrAddOnTaken, ErrCannotCancel, ErrCancelFirst:
 		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 	case ErrInsufficientFunds:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -220,6 +422,12 @@ func handleError(ctx *gin.Context, err error) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case ErrTournamentNotActive:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrInvalidPrizes, ErrTournamentStarted, ErrInvalidScoring, ErrInvalidRecurrence, ErrKnockoutTooSmall, ErrInvalidStatus, ErrNoPlaces:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotKnockout, ErrMatchNotReady, ErrRebuyNotAvailable, ErrAddOnNotAvailable:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotInMatch:
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 	default:
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 	}
@@ -1,32 +1,131 @@
 package tournament
 
 import (
+	"gamba/models"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type CreateRequest struct {
-	Name            string     `json:"name" binding:"required"`
-	Description     string     `json:"description"`
-	GameID          *uuid.UUID `json:"game_id"`
-	EntryFee        float64    `json:"entry_fee"`
-	PrizePool       float64    `json:"prize_pool"`
-	MaxParticipants int        `json:"max_participants" binding:"required"`
-	StartsAt        time.Time  `json:"starts_at" binding:"required"`
-	EndsAt          time.Time  `json:"ends_at" binding:"required"`
+	Name                  string                  `json:"name" binding:"required"`
+	Description           string                  `json:"description"`
+	GameID                *uuid.UUID              `json:"game_id"`
+	Format                models.TournamentFormat `json:"format" binding:"omitempty,oneof=leaderboard knockout"`
+	MatchRounds           int                     `json:"match_rounds" binding:"gte=0"` // best of N dice rounds per knockout match
+	ScoringMode           models.ScoringMode      `json:"scoring_mode" binding:"omitempty,oneof=total_winnings biggest_multiplier net_profit total_wagered wins best_rounds"`
+	BestRounds            int                     `json:"best_rounds" binding:"gte=0"`
+	EntryFee              float64                 `json:"entry_fee"`
+	PrizePool             float64                 `json:"prize_pool"` // seed amount
+	PoolShare             *float64                `json:"pool_share,omitempty" binding:"omitempty,gte=0,lte=1"`
+	GuaranteedPool        float64                 `json:"guaranteed_pool" binding:"gte=0"`
+	MaxParticipants       int                     `json:"max_participants" binding:"required,gt=0"`
+	MinParticipants       *int                    `json:"min_participants,omitempty" binding:"omitempty,gte=0"`
+	RegistrationAt        *time.Time              `json:"registration_at,omitempty"`
+	LateRegistrationUntil *time.Time              `json:"late_registration_until,omitempty"`
+	RebuyLimit            int                     `json:"rebuy_limit" binding:"gte=0"`
+	RebuyFee              float64                 `json:"rebuy_fee" binding:"gte=0"` // defaults to the entry fee
+	RebuyMode             models.RebuyMode        `json:"rebuy_mode" binding:"omitempty,oneof=reset top_up"`
+	RebuyScore            float64                 `json:"rebuy_score" binding:"gte=0"`
+	AddOnFee              float64                 `json:"add_on_fee" binding:"gte=0"`
+	AddOnScore            float64                 `json:"add_on_score" binding:"gte=0"`
+	StartsAt              time.Time               `json:"starts_at" binding:"required"`
+	EndsAt                time.Time               `json:"ends_at" binding:"required"`
+	Prizes                []PrizeInput            `json:"prizes,omitempty" binding:"dive"` // defaults to 50/30/20 of the pool
 }
 
 type UpdateRequest struct {
-	Name            *string    `json:"name,omitempty"`
-	Description     *string    `json:"description,omitempty"`
-	Status          *string    `json:"status,omitempty"`
-	GameID          *uuid.UUID `json:"game_id,omitempty"`
-	EntryFee        *float64   `json:"entry_fee,omitempty"`
-	PrizePool       *float64   `json:"prize_pool,omitempty"`
-	MaxParticipants *int       `json:"max_participants,omitempty"`
-	StartsAt        *time.Time `json:"starts_at,omitempty"`
-	EndsAt          *time.Time `json:"ends_at,omitempty"`
+	Name                  *string                  `json:"name,omitempty"`
+	Description           *string                  `json:"description,omitempty"`
+	Status                *string                  `json:"status,omitempty" binding:"omitempty,oneof=draft open"`
+	GameID                *uuid.UUID               `json:"game_id,omitempty"`
+	Format                *models.TournamentFormat `json:"format,omitempty" binding:"omitempty,oneof=leaderboard knockout"`
+	MatchRounds           *int                     `json:"match_rounds,omitempty" binding:"omitempty,gte=1"`
+	ScoringMode           *models.ScoringMode      `json:"scoring_mode,omitempty" binding:"omitempty,oneof=total_winnings biggest_multiplier net_profit total_wagered wins best_rounds"`
+	BestRounds            *int                     `json:"best_rounds,omitempty" binding:"omitempty,gte=0"`
+	EntryFee              *float64                 `json:"entry_fee,omitempty"`
+	PrizePool             *float64                 `json:"prize_pool,omitempty"`
+	PoolShare             *float64                 `json:"pool_share,omitempty" binding:"omitempty,gte=0,lte=1"`
+	GuaranteedPool        *float64                 `json:"guaranteed_pool,omitempty" binding:"omitempty,gte=0"`
+	MaxParticipants       *int                     `json:"max_participants,omitempty" binding:"omitempty,gt=0"`
+	MinParticipants       *int                     `json:"min_participants,omitempty" binding:"omitempty,gte=0"`
+	RegistrationAt        *time.Time               `json:"registration_at,omitempty"`
+	LateRegistrationUntil *time.Time               `json:"late_registration_until,omitempty"`
+	RebuyLimit            *int                     `json:"rebuy_limit,omitempty" binding:"omitempty,gte=0"`
+	RebuyFee              *float64                 `json:"rebuy_fee,omitempty" binding:"omitempty,gte=0"`
+	RebuyMode             *models.RebuyMode        `json:"rebuy_mode,omitempty" binding:"omitempty,oneof=reset top_up"`
+	RebuyScore            *float64                 `json:"rebuy_score,omitempty" binding:"omitempty,gte=0"`
+	AddOnFee              *float64                 `json:"add_on_fee,omitempty" binding:"omitempty,gte=0"`
+	AddOnScore            *float64                 `json:"add_on_score,omitempty" binding:"omitempty,gte=0"`
+	StartsAt              *time.Time               `json:"starts_at,omitempty"`
+	EndsAt                *time.Time               `json:"ends_at,omitempty"`
+	Prizes                *[]PrizeInput            `json:"prizes,omitempty"` // replaces the prize table before the tournament starts
+}
+
+// TemplateRequest creates a recurring tournament template. Recurrence is a
+// five field cron expression in UTC, e.g. "0 20 * * *" for every day at 20:00.
+type TemplateRequest struct {
+	Name             string                  `json:"name" binding:"required"`
+	Description      string                  `json:"description"`
+	Recurrence       string                  `json:"recurrence" binding:"required"`
+	Duration         int                     `json:"duration" binding:"required,gt=0"`  // minutes
+	RegistrationLead int                     `json:"registration_lead" binding:"gte=0"` // minutes before start
+	GameID           *uuid.UUID              `json:"game_id"`
+	Format           models.TournamentFormat `json:"format" binding:"omitempty,oneof=leaderboard knockout"`
+	MatchRounds      int                     `json:"match_rounds" binding:"gte=0"`
+	ScoringMode      models.ScoringMode      `json:"scoring_mode" binding:"omitempty,oneof=total_winnings biggest_multiplier net_profit total_wagered wins best_rounds"`
+	BestRounds       int                     `json:"best_rounds" binding:"gte=0"`
+	EntryFee         float64                 `json:"entry_fee"`
+	PrizePool        float64                 `json:"prize_pool"`
+	PoolShare        *float64                `json:"pool_share,omitempty" binding:"omitempty,gte=0,lte=1"`
+	GuaranteedPool   float64                 `json:"guaranteed_pool" binding:"gte=0"`
+	MaxParticipants  int                     `json:"max_participants" binding:"required,gt=0"`
+	MinParticipants  *int                    `json:"min_participants,omitempty" binding:"omitempty,gte=0"`
+	LateRegistration int                     `json:"late_registration" binding:"gte=0"` // minutes after start
+	RebuyLimit       int                     `json:"rebuy_limit" binding:"gte=0"`
+	RebuyFee         float64                 `json:"rebuy_fee" binding:"gte=0"`
+	RebuyMode        models.RebuyMode        `json:"rebuy_mode" binding:"omitempty,oneof=reset top_up"`
+	RebuyScore       float64                 `json:"rebuy_score" binding:"gte=0"`
+	AddOnFee         float64                 `json:"add_on_fee" binding:"gte=0"`
+	AddOnScore       float64                 `json:"add_on_score" binding:"gte=0"`
+	Prizes           []PrizeInput            `json:"prizes,omitempty" binding:"dive"`
+}
+
+// UpdateTemplateRequest changes a template for tournaments it spawns from now on
+type UpdateTemplateRequest struct {
+	Name             *string                  `json:"name,omitempty"`
+	Description      *string                  `json:"description,omitempty"`
+	Recurrence       *string                  `json:"recurrence,omitempty"`
+	Duration         *int                     `json:"duration,omitempty" binding:"omitempty,gt=0"`
+	RegistrationLead *int                     `json:"registration_lead,omitempty" binding:"omitempty,gte=0"`
+	IsPaused         *bool                    `json:"is_paused,omitempty"`
+	GameID           *uuid.UUID               `json:"game_id,omitempty"`
+	Format           *models.TournamentFormat `json:"format,omitempty" binding:"omitempty,oneof=leaderboard knockout"`
+	MatchRounds      *int                     `json:"match_rounds,omitempty" binding:"omitempty,gte=1"`
+	ScoringMode      *models.ScoringMode      `json:"scoring_mode,omitempty" binding:"omitempty,oneof=total_winnings biggest_multiplier net_profit total_wagered wins best_rounds"`
+	BestRounds       *int                     `json:"best_rounds,omitempty" binding:"omitempty,gte=0"`
+	EntryFee         *float64                 `json:"entry_fee,omitempty"`
+	PrizePool        *float64                 `json:"prize_pool,omitempty"`
+	PoolShare        *float64                 `json:"pool_share,omitempty" binding:"omitempty,gte=0,lte=1"`
+	GuaranteedPool   *float64                 `json:"guaranteed_pool,omitempty" binding:"omitempty,gte=0"`
+	MaxParticipants  *int                     `json:"max_participants,omitempty" binding:"omitempty,gt=0"`
+	MinParticipants  *int                     `json:"min_participants,omitempty" binding:"omitempty,gte=0"`
+	LateRegistration *int                     `json:"late_registration,omitempty" binding:"omitempty,gte=0"`
+	RebuyLimit       *int                     `json:"rebuy_limit,omitempty" binding:"omitempty,gte=0"`
+	RebuyFee         *float64                 `json:"rebuy_fee,omitempty" binding:"omitempty,gte=0"`
+	RebuyMode        *models.RebuyMode        `json:"rebuy_mode,omitempty" binding:"omitempty,oneof=reset top_up"`
+	RebuyScore       *float64                 `json:"rebuy_score,omitempty" binding:"omitempty,gte=0"`
+	AddOnFee         *float64                 `json:"add_on_fee,omitempty" binding:"omitempty,gte=0"`
+	AddOnScore       *float64                 `json:"add_on_score,omitempty" binding:"omitempty,gte=0"`
+	Prizes           *[]PrizeInput            `json:"prizes,omitempty"`
+}
+
+type PrizeInput struct {
+	RankFrom    int              `json:"rank_from" binding:"required,gte=1"`
+	RankTo      int              `json:"rank_to" binding:"omitempty,gte=1"` // defaults to RankFrom
+	Type        models.PrizeType `json:"type" binding:"required,oneof=percentage fixed free_spins"`
+	Amount      float64          `json:"amount" binding:"required,gt=0"`
+	Description string           `json:"description"`
 }
 
 type JoinRequest struct {
@@ -45,10 +144,64 @@ type TournamentFilter struct {
 	Offset int     `form:"offset,default=0"`
 }
 
+type LeaderboardQuery struct {
+	Limit  int `form:"limit,default=50"`
+	Offset int `form:"offset,default=0"`
+}
+
 type LeaderboardEntry struct {
 	Rank     int       `json:"rank"`
+	Position int       `json:"-"` // row in the ordered board, unlike Rank unique across ties
 	UserID   uuid.UUID `json:"user_id"`
 	UserName string    `json:"user_name"`
 	Score    float64   `json:"score"`
 	PrizeWon float64   `json:"prize_won"`
 }
+
+// Leaderboard is one page of a tournament's standings. Me and Around are the
+// caller's own entry and the entries either side of it, whichever page it is on.
+type Leaderboard struct {
+	TournamentID uuid.UUID          `json:"tournament_id"`
+	Total        int64              `json:"total"`
+	Limit        int                `json:"limit"`
+	Offset       int                `json:"offset"`
+	Entries      []LeaderboardEntry `json:"entries"`
+	Me           *LeaderboardEntry  `json:"me,omitempty"`
+	Around       []LeaderboardEntry `json:"around,omitempty"`
+}
+
+// RankChange is broadcast to tournament subscribers when a score moves
+type RankChange struct {
+	TournamentID uuid.UUID `json:"tournament_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Score        float64   `json:"score"`
+	PreviousRank int       `json:"previous_rank"`
+	Rank         int       `json:"rank"`
+}
+
+// BracketRound lists the matches of one knockout round in bracket order
+type BracketRound struct {
+	Round   int                      `json:"round"`
+	Matches []models.TournamentMatch `json:"matches"`
+}
+
+type Bracket struct {
+	TournamentID uuid.UUID               `json:"tournament_id"`
+	Status       models.TournamentStatus `json:"status"`
+	ChampionID   *uuid.UUID              `json:"champion_id,omitempty"`
+	Rounds       []BracketRound          `json:"rounds"`
+}
+
+// MatchRoundResult is the outcome of one dice round of a knockout match
+type MatchRoundResult struct {
+	Match models.TournamentMatch      `json:"match"`
+	Round models.TournamentMatchRound `json:"round"`
+}
+
+// TournamentCancelled tells participants a tournament was called off, Refund
+// being what they got back
+type TournamentCancelled struct {
+	TournamentID uuid.UUID `json:"tournament_id"`
+	Name         string    `json:"name"`
+	Refund       float64   `json:"refund,omitempty"`
+}
@@ -0,0 +1,129 @@
+package tournament
+
+import (
+	"errors"
+
+	"gamba/chat"
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// aroundMe is how many entries either side of the caller Leaderboard.Around holds
+const aroundMe = 2
+
+// GetLeaderboard returns a page of the tournament's standings ranked by the
+// database, with the caller's own position and neighbours
+func (s *Service) GetLeaderboard(tournamentID, userID uuid.UUID, query *LeaderboardQuery) (*Leaderboard, error) {
+	if err := s.db.Select("id").First(&models.Tournament{}, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTournamentNotFound
+		}
+		return nil, err
+	}
+
+	leaderboard := &Leaderboard{
+		TournamentID: tournamentID,
+		Limit:        query.Limit,
+		Offset:       query.Offset,
+		Entries:      []LeaderboardEntry{},
+	}
+
+	if err := s.db.Model(&models.TournamentParticipant{}).
+		Where("tournament_id = ?", tournamentID).
+		Count(&leaderboard.Total).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Table("(?) AS ranked", s.ranked(tournamentID)).
+		Order("position ASC").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&leaderboard.Entries).Error; err != nil {
+		return nil, err
+	}
+
+	var me []LeaderboardEntry
+	if err := s.db.Table("(?) AS ranked", s.ranked(tournamentID)).
+		Where("user_id = ?", userID).
+		Scan(&me).Error; err != nil {
+		return nil, err
+	}
+	if len(me) == 0 {
+		return leaderboard, nil
+	}
+	leaderboard.Me = &me[0]
+
+	if err := s.db.Table("(?) AS ranked", s.ranked(tournamentID)).
+		Where("position BETWEEN ? AND ?", me[0].Position-aroundMe, me[0].Position+aroundMe).
+		Order("position ASC").
+		Scan(&leaderboard.Around).Error; err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+// ranked orders a tournament's participants by score, tied scores sharing a
+// rank the same way prizes are split
+func (s *Service) ranked(tournamentID uuid.UUID) *gorm.DB {
+	return s.db.Table("tournament_participants AS p").
+		Select("p.user_id, u.username AS user_name, p.score, p.prize_won, "+
+			"RANK() OVER (ORDER BY p.score DESC) AS rank, "+
+			"ROW_NUMBER() OVER (ORDER BY p.score DESC, p.joined_at ASC) AS position").
+		Joins("JOIN users AS u ON u.id = p.user_id").
+		Where("p.tournament_id = ?", tournamentID)
+}
+
+// PublishRankChanges broadcasts score and rank movements to everyone watching
+// the tournaments. Call it once the scores have been committed.
+func (s *Service) PublishRankChanges(changes []RankChange) {
+	if s.hub == nil {
+		return
+	}
+
+	for _, change := range changes {
+		s.hub.Publish(tournamentTopic(change.TournamentID), chat.WSMessage{
+			Type:    "rank_changed",
+			Payload: change,
+		})
+	}
+}
+
+// rescore sets a participant's score and reports how their rank moved, nil
+// when the score is unchanged
+func rescore(tx *gorm.DB, participant *models.TournamentParticipant, score float64) (*RankChange, error) {
+	if score == participant.Score {
+		return nil, nil
+	}
+
+	previous, err := rankOf(tx, participant.TournamentID, participant.Score)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(participant).Update("score", score).Error; err != nil {
+		return nil, err
+	}
+	rank, err := rankOf(tx, participant.TournamentID, score)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RankChange{
+		TournamentID: participant.TournamentID,
+		UserID:       participant.UserID,
+		Score:        score,
+		PreviousRank: previous,
+		Rank:         rank,
+	}, nil
+}
+
+// rankOf is the rank a score holds in a tournament, one more than the number
+// of participants strictly ahead of it
+func rankOf(tx *gorm.DB, tournamentID uuid.UUID, score float64) (int, error) {
+	var ahead int64
+	err := tx.Model(&models.TournamentParticipant{}).
+		Where("tournament_id = ? AND score > ?", tournamentID, score).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
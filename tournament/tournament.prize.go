@@ -0,0 +1,168 @@
+package tournament
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultPrizes is used for tournaments created without a prize table
+var defaultPrizes = []PrizeInput{
+	{RankFrom: 1, RankTo: 1, Type: models.PrizeTypePercentage, Amount: 50},
+	{RankFrom: 2, RankTo: 2, Type: models.PrizeTypePercentage, Amount: 30},
+	{RankFrom: 3, RankTo: 3, Type: models.PrizeTypePercentage, Amount: 20},
+}
+
+// placement is where a participant finished and what they won
+type placement struct {
+	participant models.TournamentParticipant
+	rank        int
+	prize       float64
+	award       string
+}
+
+// validatePrizes checks rank ranges don't overlap, fit the field and that
+// percentages don't pay out more than the pool
+func validatePrizes(prizes []PrizeInput, maxParticipants int) error {
+	sorted := make([]PrizeInput, len(prizes))
+	copy(sorted, prizes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RankFrom < sorted[j].RankFrom })
+
+	percentage := 0.0
+	last := 0
+	for _, p := range sorted {
+		to := p.RankTo
+		if to == 0 {
+			to = p.RankFrom
+		}
+		if p.RankFrom < 1 || to < p.RankFrom || to > maxParticipants || p.RankFrom <= last || p.Amount <= 0 {
+			return ErrInvalidPrizes
+		}
+		last = to
+
+		switch p.Type {
+		case models.PrizeTypePercentage:
+			percentage += p.Amount * float64(to-p.RankFrom+1)
+		case models.PrizeTypeFixed, models.PrizeTypeFreeSpins:
+		default:
+			return ErrInvalidPrizes
+		}
+	}
+
+	if percentage > 100 {
+		return ErrInvalidPrizes
+	}
+	return nil
+}
+
+// savePrizes replaces a tournament's prize table
+func savePrizes(tx *gorm.DB, tournamentID uuid.UUID, prizes []PrizeInput) ([]models.TournamentPrize, error) {
+	if err := tx.Where("tournament_id = ?", tournamentID).Delete(&models.TournamentPrize{}).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]models.TournamentPrize, 0, len(prizes))
+	for _, p := range prizes {
+		to := p.RankTo
+		if to == 0 {
+			to = p.RankFrom
+		}
+		rows = append(rows, models.TournamentPrize{
+			ID:           uuid.New(),
+			TournamentID: tournamentID,
+			RankFrom:     p.RankFrom,
+			RankTo:       to,
+			Type:         p.Type,
+			Amount:       p.Amount,
+			Description:  p.Description,
+		})
+	}
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// prizeAt returns the prize for a rank, if any
+func prizeAt(prizes []models.TournamentPrize, rank int) *models.TournamentPrize {
+	for i := range prizes {
+		if rank >= prizes[i].RankFrom && rank <= prizes[i].RankTo {
+			return &prizes[i]
+		}
+	}
+	return nil
+}
+
+// cashAt is the cash paid for a rank from the given pool
+func cashAt(prizes []models.TournamentPrize, rank int, pool float64) float64 {
+	p := prizeAt(prizes, rank)
+	if p == nil {
+		return 0
+	}
+	switch p.Type {
+	case models.PrizeTypePercentage:
+		return pool * p.Amount / 100
+	case models.PrizeTypeFixed:
+		return p.Amount
+	default:
+		return 0
+	}
+}
+
+// awardAt describes the non-cash prize for a rank, if any
+func awardAt(prizes []models.TournamentPrize, rank int) string {
+	p := prizeAt(prizes, rank)
+	if p == nil || p.Type != models.PrizeTypeFreeSpins {
+		return ""
+	}
+	if p.Description != "" {
+		return p.Description
+	}
+	return fmt.Sprintf("%d free spins", int(p.Amount))
+}
+
+// distribute ranks participants by score. Tied players share the best rank
+// among them and split the cash for all the ranks they cover; non-cash awards
+// can't be split so each of them gets the best rank's award.
+func distribute(participants []models.TournamentParticipant, prizes []models.TournamentPrize, pool float64) []placement {
+	sorted := make([]models.TournamentParticipant, len(participants))
+	copy(sorted, participants)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+
+	placements := make([]placement, 0, len(sorted))
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j].Score == sorted[i].Score {
+			j++
+		}
+
+		rank := i + 1
+		cash := 0.0
+		for r := rank; r <= j; r++ {
+			cash += cashAt(prizes, r, pool)
+		}
+		share := math.Floor(cash/float64(j-i)*100) / 100
+		award := awardAt(prizes, rank)
+
+		for _, p := range sorted[i:j] {
+			placements = append(placements, placement{
+				participant: p,
+				rank:        rank,
+				prize:       share,
+				award:       award,
+			})
+		}
+		i = j
+	}
+	return placements
+}
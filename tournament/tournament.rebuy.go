@@ -0,0 +1,201 @@
+package tournament
+
+import (
+	"errors"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rebuy charges the rebuy fee again to reset the participant's score or top
+// it up, up to the tournament's RebuyLimit
+func (s *Service) Rebuy(userID, tournamentID uuid.UUID) (*models.TournamentParticipant, error) {
+	tournament, participant, err := s.runningEntry(userID, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.RebuyLimit == 0 {
+		return nil, ErrRebuyNotAvailable
+	}
+
+	var change *RankChange
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEntry(tx, tournament, participant); err != nil {
+			return err
+		}
+		if participant.Rebuys >= tournament.RebuyLimit {
+			return ErrRebuyLimitReached
+		}
+
+		fee := tournament.RebuyFee
+		if fee == 0 {
+			fee = tournament.EntryFee
+		}
+		if fee > 0 {
+			if err := s.charge(tx, userID, tournament, fee, models.TransactionTypeTournamentRebuy, "Tournament rebuy: "+tournament.Name); err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{
+			"rebuys":     participant.Rebuys + 1,
+			"total_paid": participant.TotalPaid + fee,
+			"pool_paid":  participant.PoolPaid + fee*tournament.PoolShare,
+		}
+		score := participant.Score + tournament.RebuyScore
+		if tournament.RebuyMode == models.RebuyModeReset {
+			// Start over: only bets from now count and earlier points are dropped
+			updates["scoring_from"] = time.Now()
+			updates["score_adjustment"] = 0
+			score = 0
+		} else {
+			updates["score_adjustment"] = participant.ScoreAdjustment + tournament.RebuyScore
+		}
+		if err := tx.Model(participant).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		var err error
+		change, err = rescore(tx, participant, score)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if change != nil {
+		s.PublishRankChanges([]RankChange{*change})
+	}
+	return participant, nil
+}
+
+// AddOn charges the one-off add-on fee for AddOnScore extra points
+func (s *Service) AddOn(userID, tournamentID uuid.UUID) (*models.TournamentParticipant, error) {
+	tournament, participant, err := s.runningEntry(userID, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.AddOnFee == 0 {
+		return nil, ErrAddOnNotAvailable
+	}
+
+	var change *RankChange
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEntry(tx, tournament, participant); err != nil {
+			return err
+		}
+		if participant.AddOn {
+			return ErrAddOnTaken
+		}
+
+		if err := s.charge(tx, userID, tournament, tournament.AddOnFee, models.TransactionTypeTournamentAddOn, "Tournament add-on: "+tournament.Name); err != nil {
+			return err
+		}
+
+		if err := tx.Model(participant).Updates(map[string]interface{}{
+			"add_on":           true,
+			"total_paid":       participant.TotalPaid + tournament.AddOnFee,
+			"pool_paid":        participant.PoolPaid + tournament.AddOnFee*tournament.PoolShare,
+			"score_adjustment": participant.ScoreAdjustment + tournament.AddOnScore,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		change, err = rescore(tx, participant, participant.Score+tournament.AddOnScore)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if change != nil {
+		s.PublishRankChanges([]RankChange{*change})
+	}
+	return participant, nil
+}
+
+// runningEntry loads a running leaderboard tournament and the user's entry in it
+func (s *Service) runningEntry(userID, tournamentID uuid.UUID) (*models.Tournament, *models.TournamentParticipant, error) {
+	var tournament models.Tournament
+	if err := s.db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrTournamentNotFound
+		}
+		return nil, nil, err
+	}
+	if tournament.Status != models.TournamentStatusInProgress || !time.Now().Before(tournament.EndsAt) {
+		return nil, nil, ErrTournamentNotActive
+	}
+	if tournament.Format != models.TournamentFormatLeaderboard {
+		return nil, nil, ErrRebuyNotAvailable
+	}
+
+	var participant models.TournamentParticipant
+	if err := s.db.Where("tournament_id = ? AND user_id = ?", tournamentID, userID).First(&participant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotParticipant
+		}
+		return nil, nil, err
+	}
+	return &tournament, &participant, nil
+}
+
+// lockEntry re-reads a running tournament and entry inside tx, locking both
+// rows so rebuys, add-ons and a cancel or end of the tournament go one at a time
+func lockEntry(tx *gorm.DB, tournament *models.Tournament, participant *models.TournamentParticipant) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(tournament, "id = ?", tournament.ID).Error; err != nil {
+		return err
+	}
+	if tournament.Status != models.TournamentStatusInProgress || !time.Now().Before(tournament.EndsAt) {
+		return ErrTournamentNotActive
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(participant, "id = ?", participant.ID).Error
+}
+
+// lateRegistrationOpen reports whether a running tournament still takes
+// entries. Knockout brackets are seeded at the start so never do.
+func lateRegistrationOpen(tournament *models.Tournament) bool {
+	now := time.Now()
+	return tournament.Status == models.TournamentStatusInProgress &&
+		tournament.Format == models.TournamentFormatLeaderboard &&
+		tournament.LateRegistrationUntil != nil &&
+		now.Before(*tournament.LateRegistrationUntil) &&
+		now.Before(tournament.EndsAt)
+}
+
+// charge takes a fee from a user's balance, records the transaction against
+// the tournament and pays the pool's share of it in, the rest is rake
+func (s *Service) charge(tx *gorm.DB, userID uuid.UUID, tournament *models.Tournament, amount float64, txType models.TransactionType, description string) error {
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if user.Balance < amount {
+		return ErrInsufficientFunds
+	}
+
+	if err := tx.Model(&user).Update("balance", user.Balance-amount).Error; err != nil {
+		return err
+	}
+
+	transaction := models.Transaction{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Type:          txType,
+		Status:        models.TransactionStatusCompleted,
+		Amount:        -amount,
+		ReferenceID:   &tournament.ID,
+		ReferenceType: strPtr("tournament"),
+		Description:   description,
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(tournament).Update("prize_pool", gorm.Expr("prize_pool + ?", amount*tournament.PoolShare)).Error
+}
@@ -0,0 +1,150 @@
+package tournament
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week, evaluated in UTC. Each field accepts *, single values,
+// ranges, lists and /steps, and the @hourly, @daily, @weekly and @monthly
+// shorthands are understood.
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+var scheduleShorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+func parseSchedule(expr string) (*schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if full, ok := scheduleShorthands[expr]; ok {
+		expr = full
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, ErrInvalidRecurrence
+	}
+
+	var sched schedule
+	var err error
+	if sched.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if sched.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if sched.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if sched.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if sched.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 is Sunday as well as 0
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1
+	}
+	sched.anyDom = fields[2] == "*"
+	sched.anyDow = fields[4] == "*"
+	return &sched, nil
+}
+
+// parseField turns one cron field into a bit set of the values it allows
+func parseField(field string, lo, hi int) (uint64, error) {
+	var bitsSet uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, ErrInvalidRecurrence
+			}
+			step = n
+			part = part[:i]
+		}
+
+		from, to := lo, hi
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			from, err1 = strconv.Atoi(bounds[0])
+			to, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, ErrInvalidRecurrence
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, ErrInvalidRecurrence
+			}
+			from = n
+			if step == 1 {
+				to = n
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, ErrInvalidRecurrence
+		}
+
+		for v := from; v <= to; v += step {
+			bitsSet |= 1 << v
+		}
+	}
+	return bitsSet, nil
+}
+
+// next returns the first time after t the schedule fires, or the zero time
+// if it never does within five years (e.g. 30 February)
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted either may match
+func (s *schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,136 @@
+package tournament
+
+import (
+	"log"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RunScheduler moves tournaments through their lifecycle on a fixed interval
+func (s *Service) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.spawnFromTemplates()
+		s.openRegistrations()
+		s.startDueTournaments()
+		s.endDueTournaments()
+	}
+}
+
+// openRegistrations opens drafts whose registration time has passed
+func (s *Service) openRegistrations() {
+	result := s.db.Model(&models.Tournament{}).
+		Where("status = ? AND registration_at <= ? AND starts_at > ?", models.TournamentStatusDraft, time.Now(), time.Now()).
+		Update("status", models.TournamentStatusOpen)
+	if result.Error != nil {
+		log.Printf("Error opening tournament registrations: %v", result.Error)
+	}
+}
+
+// startDueTournaments starts open tournaments at StartsAt, seeding knockout
+// brackets, or cancels them with refunds if too few players joined
+func (s *Service) startDueTournaments() {
+	var tournaments []models.Tournament
+	if err := s.db.Preload("Participants").
+		Where("status = ? AND starts_at <= ?", models.TournamentStatusOpen, time.Now()).
+		Find(&tournaments).Error; err != nil {
+		log.Printf("Error loading due tournaments: %v", err)
+		return
+	}
+
+	for _, tournament := range tournaments {
+		required := tournament.MinParticipants
+		if tournament.Format == models.TournamentFormatKnockout {
+			// there's no bracket to seed without two players
+			required = max(required, 2)
+		}
+		if len(tournament.Participants) < required {
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				return s.cancelTournament(tx, &tournament)
+			})
+			if err != nil {
+				log.Printf("Error cancelling tournament %s: %v", tournament.ID, err)
+			} else {
+				log.Printf("Cancelled tournament %s with %d of %d required participants",
+					tournament.ID, len(tournament.Participants), required)
+				s.notifyCancelled(&tournament)
+			}
+			continue
+		}
+
+		started := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// claim the start so a concurrent cancel or a second scheduler
+			// run can't start or seed it again
+			result := tx.Model(&models.Tournament{}).
+				Where("id = ? AND status = ?", tournament.ID, models.TournamentStatusOpen).
+				Update("status", models.TournamentStatusInProgress)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+			started = true
+			tournament.Status = models.TournamentStatusInProgress
+			if tournament.Format != models.TournamentFormatKnockout {
+				return nil
+			}
+			return s.seedBracket(tx, &tournament)
+		})
+		if err != nil {
+			log.Printf("Error starting tournament %s: %v", tournament.ID, err)
+			continue
+		}
+		if started && tournament.Format == models.TournamentFormatKnockout {
+			s.publishBracket(tournament.ID)
+		}
+	}
+}
+
+// endDueTournaments ends running tournaments at EndsAt and pays out prizes
+func (s *Service) endDueTournaments() {
+	var ids []uuid.UUID
+	if err := s.db.Model(&models.Tournament{}).
+		Where("status = ? AND ends_at <= ?", models.TournamentStatusInProgress, time.Now()).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("Error loading finished tournaments: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := s.EndTournament(id); err != nil {
+			log.Printf("Error ending tournament %s: %v", id, err)
+		}
+	}
+}
+
+// credit adds funds to a user's balance and records the transaction against a tournament
+func (s *Service) credit(tx *gorm.DB, userID uuid.UUID, tournament *models.Tournament, amount float64, txType models.TransactionType, description string) error {
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&user).Update("balance", user.Balance+amount).Error; err != nil {
+		return err
+	}
+
+	transaction := models.Transaction{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Type:          txType,
+		Status:        models.TransactionStatusCompleted,
+		Amount:        amount,
+		ReferenceID:   &tournament.ID,
+		ReferenceType: strPtr("tournament"),
+		Description:   description,
+	}
+	return tx.Create(&transaction).Error
+}
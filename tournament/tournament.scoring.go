@@ -0,0 +1,84 @@
+package tournament
+
+import (
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecordPlay rescores a player in every running tournament on the game from
+// their bets inside each tournament's window. It is called from within the
+// game's transaction so the score always matches the recorded bets; the
+// returned changes are for PublishRankChanges once that has committed.
+func (s *Service) RecordPlay(tx *gorm.DB, userID, gameID uuid.UUID) ([]RankChange, error) {
+	now := time.Now()
+
+	var tournaments []models.Tournament
+	if err := tx.Joins("JOIN tournament_participants ON tournament_participants.tournament_id = tournaments.id").
+		Where("tournaments.format = ?", models.TournamentFormatLeaderboard).
+		Where("tournaments.game_id = ? AND tournaments.status = ? AND tournaments.starts_at <= ? AND tournaments.ends_at >= ?",
+			gameID, models.TournamentStatusInProgress, now, now).
+		Where("tournament_participants.user_id = ?", userID).
+		Find(&tournaments).Error; err != nil {
+		return nil, err
+	}
+
+	var changes []RankChange
+	for _, tournament := range tournaments {
+		var participant models.TournamentParticipant
+		if err := tx.Where("tournament_id = ? AND user_id = ?", tournament.ID, userID).First(&participant).Error; err != nil {
+			return nil, err
+		}
+
+		score, err := s.scoreBets(tx, &tournament, &participant)
+		if err != nil {
+			return nil, err
+		}
+
+		change, err := rescore(tx, &participant, score+participant.ScoreAdjustment)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// scoreBets computes a player's score from their game bets in the tournament
+// window, from ScoringFrom if they joined late or rebought with a reset
+func (s *Service) scoreBets(tx *gorm.DB, tournament *models.Tournament, participant *models.TournamentParticipant) (float64, error) {
+	from := tournament.StartsAt
+	if participant.ScoringFrom != nil && participant.ScoringFrom.After(from) {
+		from = *participant.ScoringFrom
+	}
+
+	bets := tx.Model(&models.Bet{}).
+		Where("user_id = ? AND game_id = ? AND type = ? AND created_at BETWEEN ? AND ?",
+			participant.UserID, tournament.GameID, models.BetTypeGame, from, tournament.EndsAt)
+
+	var score float64
+	var err error
+
+	switch tournament.ScoringMode {
+	case models.ScoringModeBiggestMultiplier:
+		err = bets.Select("COALESCE(MAX(payout / NULLIF(amount, 0)), 0)").Scan(&score).Error
+	case models.ScoringModeNetProfit:
+		err = bets.Select("COALESCE(SUM(payout - amount), 0)").Scan(&score).Error
+	case models.ScoringModeTotalWagered:
+		err = bets.Select("COALESCE(SUM(amount), 0)").Scan(&score).Error
+	case models.ScoringModeWins:
+		err = bets.Where("status = ?", models.BetStatusWon).Select("COUNT(*)").Scan(&score).Error
+	case models.ScoringModeBestRounds:
+		best := bets.Select("payout - amount AS profit").Order("profit DESC").Limit(tournament.BestRounds)
+		err = tx.Table("(?) AS best", best).Select("COALESCE(SUM(profit), 0)").Scan(&score).Error
+	default:
+		err = bets.Select("COALESCE(SUM(payout), 0)").Scan(&score).Error
+	}
+
+	return score, err
+}
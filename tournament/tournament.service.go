@@ -2,12 +2,15 @@ package tournament
 
 import (
 	"errors"
-	"sort"
+	"math"
+	"time"
 
+	"gamba/chat"
 	"gamba/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -18,14 +21,51 @@ var (
 	ErrInsufficientFunds   = errors.New("insufficient funds for entry fee")
 	ErrNotParticipant      = errors.New("user is not a participant")
 	ErrTournamentNotActive = errors.New("tournament is not active")
+	ErrInvalidPrizes       = errors.New("invalid prize table")
+	ErrTournamentStarted   = errors.New("tournament has already started")
+	ErrInvalidScoring      = errors.New("best_rounds scoring needs best_rounds above zero")
+	ErrNotKnockout         = errors.New("tournament is not a knockout")
+	ErrMatchNotFound       = errors.New("match not found")
+	ErrMatchNotReady       = errors.New("match is not ready to play")
+	ErrNotInMatch          = errors.New("user is not playing this match")
+	ErrKnockoutTooSmall    = errors.New("a knockout needs at least two players")
+	ErrTemplateNotFound    = errors.New("tournament template not found")
+	ErrInvalidRecurrence   = errors.New("invalid recurrence rule")
+	ErrRebuyNotAvailable   = errors.New("rebuys are not available in this tournament")
+	ErrRebuyLimitReached   = errors.New("rebuy limit reached")
+	ErrAddOnNotAvailable   = errors.New("add-ons are not available in this tournament")
+	ErrAddOnTaken          = errors.New("add-on already taken")
+	ErrCannotCancel        = errors.New("tournament is already completed or cancelled")
+	ErrCancelFirst         = errors.New("cancel the tournament before deleting it")
+	ErrInvalidStatus       = errors.New("status can only be changed between draft and open")
+	ErrNoPlaces            = errors.New("max_participants must be at least one")
+
+	errAlreadySpawned = errors.New("tournament already spawned for this start time")
 )
 
+// Config holds tunable settings for tournaments
+type Config struct {
+	MinParticipants int           // default minimum entrants for a tournament to start
+	PoolShare       float64       // default fraction of entry fees paid into the prize pool
+	SpawnAhead      time.Duration // how far ahead templates create their tournaments
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MinParticipants: 2,
+		PoolShare:       0.9,
+		SpawnAhead:      24 * time.Hour,
+	}
+}
+
 type Service struct {
-	db *gorm.DB
+	db     *gorm.DB
+	hub    *chat.Hub
+	config Config
 }
 
-func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+func NewService(db *gorm.DB, hub *chat.Hub, config Config) *Service {
+	return &Service{db: db, hub: hub, config: config}
 }
 
 // GetAll returns tournaments with optional filters
@@ -45,39 +85,128 @@ func (s *Service) GetAll(filter *TournamentFilter) ([]models.Tournament, error)
 	if err := query.Find(&tournaments).Error; err != nil {
 		return nil, err
 	}
+	for i := range tournaments {
+		tournaments[i].CurrentPool = currentPool(&tournaments[i])
+	}
 	return tournaments, nil
 }
 
 // GetByID returns a tournament by ID
 func (s *Service) GetByID(id uuid.UUID) (*models.Tournament, error) {
 	var tournament models.Tournament
-	if err := s.db.Preload("Participants").First(&tournament, "id = ?", id).Error; err != nil {
+	if err := s.db.Preload("Participants").Preload("Prizes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("rank_from ASC")
+	}).First(&tournament, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrTournamentNotFound
 		}
 		return nil, err
 	}
+	tournament.CurrentPool = currentPool(&tournament)
 	return &tournament, nil
 }
 
 // Create creates a new tournament (admin only)
 func (s *Service) Create(req *CreateRequest) (*models.Tournament, error) {
+	var tournament *models.Tournament
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		tournament, err = s.create(tx, req, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tournament, nil
+}
+
+// create validates and saves a tournament with its prize table, templateID
+// is set when it was spawned from a recurring template
+func (s *Service) create(tx *gorm.DB, req *CreateRequest, templateID *uuid.UUID) (*models.Tournament, error) {
 	tournament := models.Tournament{
-		ID:              uuid.New(),
-		Name:            req.Name,
-		Description:     req.Description,
-		Status:          models.TournamentStatusDraft,
-		GameID:          req.GameID,
-		EntryFee:        req.EntryFee,
-		PrizePool:       req.PrizePool,
-		MaxParticipants: req.MaxParticipants,
-		StartsAt:        req.StartsAt,
-		EndsAt:          req.EndsAt,
-	}
-
-	if err := s.db.Create(&tournament).Error; err != nil {
+		ID:                    uuid.New(),
+		Name:                  req.Name,
+		Description:           req.Description,
+		Status:                models.TournamentStatusDraft,
+		GameID:                req.GameID,
+		TemplateID:            templateID,
+		Format:                models.TournamentFormatLeaderboard,
+		MatchRounds:           3,
+		ScoringMode:           models.ScoringModeTotalWinnings,
+		BestRounds:            req.BestRounds,
+		EntryFee:              req.EntryFee,
+		PrizePool:             req.PrizePool,
+		PoolShare:             s.config.PoolShare,
+		GuaranteedPool:        req.GuaranteedPool,
+		MaxParticipants:       req.MaxParticipants,
+		MinParticipants:       s.config.MinParticipants,
+		RegistrationAt:        req.RegistrationAt,
+		LateRegistrationUntil: req.LateRegistrationUntil,
+		RebuyLimit:            req.RebuyLimit,
+		RebuyFee:              req.RebuyFee,
+		RebuyMode:             models.RebuyModeReset,
+		RebuyScore:            req.RebuyScore,
+		AddOnFee:              req.AddOnFee,
+		AddOnScore:            req.AddOnScore,
+		StartsAt:              req.StartsAt,
+		EndsAt:                req.EndsAt,
+	}
+	if req.MinParticipants != nil {
+		tournament.MinParticipants = *req.MinParticipants
+	}
+	if req.PoolShare != nil {
+		tournament.PoolShare = *req.PoolShare
+	}
+	if req.Format != "" {
+		tournament.Format = req.Format
+	}
+	if req.MatchRounds > 0 {
+		tournament.MatchRounds = req.MatchRounds
+	}
+	if req.ScoringMode != "" {
+		tournament.ScoringMode = req.ScoringMode
+	}
+	if req.RebuyMode != "" {
+		tournament.RebuyMode = req.RebuyMode
+	}
+	if tournament.ScoringMode == models.ScoringModeBestRounds && tournament.BestRounds <= 0 {
+		return nil, ErrInvalidScoring
+	}
+	if tournament.Format == models.TournamentFormatKnockout && tournament.MinParticipants < 2 {
+		return nil, ErrKnockoutTooSmall
+	}
+	if req.MaxParticipants < 1 {
+		return nil, ErrNoPlaces
+	}
+
+	prizes := req.Prizes
+	if len(prizes) == 0 {
+		prizes = defaultPrizes[:min(len(defaultPrizes), req.MaxParticipants)]
+	}
+	if err := validatePrizes(prizes, req.MaxParticipants); err != nil {
 		return nil, err
 	}
+
+	query := tx
+	if templateID != nil {
+		// A template spawns one tournament per start time, another scheduler
+		// run may have got there first
+		query = tx.Clauses(clause.OnConflict{DoNothing: true})
+	}
+	result := query.Create(&tournament)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errAlreadySpawned
+	}
+
+	var err error
+	tournament.Prizes, err = savePrizes(tx, tournament.ID, prizes)
+	if err != nil {
+		return nil, err
+	}
+	tournament.CurrentPool = currentPool(&tournament)
 	return &tournament, nil
 }
 
@@ -99,20 +228,102 @@ func (s *Service) Update(id uuid.UUID, req *UpdateRequest) (*models.Tournament,
 		updates["description"] = *req.Description
 	}
 	if req.Status != nil {
-		updates["status"] = *req.Status
+		// starting, ending and cancelling go through the scheduler, EndTournament
+		// and Cancel, which seed brackets, pay prizes and refund entries
+		status := models.TournamentStatus(*req.Status)
+		if status != models.TournamentStatusDraft && status != models.TournamentStatusOpen {
+			return nil, ErrInvalidStatus
+		}
+		if tournament.Status != models.TournamentStatusDraft && tournament.Status != models.TournamentStatusOpen {
+			return nil, ErrTournamentStarted
+		}
+		updates["status"] = status
 	}
 	if req.GameID != nil {
 		updates["game_id"] = *req.GameID
 	}
+	if req.Format != nil || req.MatchRounds != nil {
+		if tournament.Status != models.TournamentStatusDraft && tournament.Status != models.TournamentStatusOpen {
+			return nil, ErrTournamentStarted
+		}
+		if req.Format != nil {
+			updates["format"] = *req.Format
+		}
+		if req.MatchRounds != nil {
+			updates["match_rounds"] = *req.MatchRounds
+		}
+	}
+	if req.ScoringMode != nil || req.BestRounds != nil {
+		if tournament.Status != models.TournamentStatusDraft && tournament.Status != models.TournamentStatusOpen {
+			return nil, ErrTournamentStarted
+		}
+		mode, rounds := tournament.ScoringMode, tournament.BestRounds
+		if req.ScoringMode != nil {
+			mode = *req.ScoringMode
+		}
+		if req.BestRounds != nil {
+			rounds = *req.BestRounds
+		}
+		if mode == models.ScoringModeBestRounds && rounds <= 0 {
+			return nil, ErrInvalidScoring
+		}
+		updates["scoring_mode"] = mode
+		updates["best_rounds"] = rounds
+	}
 	if req.EntryFee != nil {
 		updates["entry_fee"] = *req.EntryFee
 	}
 	if req.PrizePool != nil {
 		updates["prize_pool"] = *req.PrizePool
 	}
+	if req.PoolShare != nil {
+		updates["pool_share"] = *req.PoolShare
+	}
+	if req.GuaranteedPool != nil {
+		updates["guaranteed_pool"] = *req.GuaranteedPool
+	}
 	if req.MaxParticipants != nil {
 		updates["max_participants"] = *req.MaxParticipants
 	}
+	if req.MinParticipants != nil {
+		updates["min_participants"] = *req.MinParticipants
+	}
+	if req.Format != nil || req.MinParticipants != nil {
+		format, minParticipants := tournament.Format, tournament.MinParticipants
+		if req.Format != nil {
+			format = *req.Format
+		}
+		if req.MinParticipants != nil {
+			minParticipants = *req.MinParticipants
+		}
+		if format == models.TournamentFormatKnockout && minParticipants < 2 {
+			return nil, ErrKnockoutTooSmall
+		}
+	}
+	if req.RegistrationAt != nil {
+		updates["registration_at"] = *req.RegistrationAt
+	}
+	if req.LateRegistrationUntil != nil {
+		updates["late_registration_until"] = *req.LateRegistrationUntil
+	}
+	if req.RebuyLimit != nil {
+		updates["rebuy_limit"] = *req.RebuyLimit
+	}
+	if req.RebuyFee != nil {
+		updates["rebuy_fee"] = *req.RebuyFee
+	}
+	if req.RebuyMode != nil {
+		updates["rebuy_mode"] = *req.RebuyMode
+	}
+	if req.RebuyScore != nil {
+		updates["rebuy_score"] = *req.RebuyScore
+	}
+	if req.AddOnFee != nil {
+		updates["add_on_fee"] = *req.AddOnFee
+	}
+	if req.AddOnScore != nil {
+		updates["add_on_score"] = *req.AddOnScore
+	}
 	if req.StartsAt != nil {
 		updates["starts_at"] = *req.StartsAt
 	}
@@ -120,18 +331,65 @@ func (s *Service) Update(id uuid.UUID, req *UpdateRequest) (*models.Tournament,
 		updates["ends_at"] = *req.EndsAt
 	}
 
-	if len(updates) > 0 {
-		if err := s.db.Model(&tournament).Updates(updates).Error; err != nil {
+	if req.Prizes != nil {
+		if tournament.Status != models.TournamentStatusDraft && tournament.Status != models.TournamentStatusOpen {
+			return nil, ErrTournamentStarted
+		}
+		maxParticipants := tournament.MaxParticipants
+		if req.MaxParticipants != nil {
+			maxParticipants = *req.MaxParticipants
+		}
+		if err := validatePrizes(*req.Prizes, maxParticipants); err != nil {
 			return nil, err
 		}
 	}
 
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&tournament).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Prizes == nil {
+			return nil
+		}
+
+		var err error
+		tournament.Prizes, err = savePrizes(tx, tournament.ID, *req.Prizes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tournament.CurrentPool = currentPool(&tournament)
 	return &tournament, nil
 }
 
-// Delete deletes a tournament (admin only)
+// Delete deletes a tournament (admin only). Tournaments holding entry fees
+// must be cancelled first so participants are refunded.
 func (s *Service) Delete(id uuid.UUID) error {
-	result := s.db.Delete(&models.Tournament{}, "id = ?", id)
+	var tournament models.Tournament
+	if err := s.db.First(&tournament, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTournamentNotFound
+		}
+		return err
+	}
+
+	if tournament.Status != models.TournamentStatusCompleted && tournament.Status != models.TournamentStatusCancelled {
+		var paid int64
+		if err := s.db.Model(&models.TournamentParticipant{}).
+			Where("tournament_id = ? AND total_paid > 0", id).
+			Count(&paid).Error; err != nil {
+			return err
+		}
+		if paid > 0 {
+			return ErrCancelFirst
+		}
+	}
+
+	result := s.db.Delete(&tournament)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -151,8 +409,9 @@ func (s *Service) Join(userID uuid.UUID, req *JoinRequest) (*models.TournamentPa
 		return nil, err
 	}
 
-	// Check if tournament is open
-	if tournament.Status != models.TournamentStatusOpen {
+	// Check if tournament is open, or running within late registration
+	late := lateRegistrationOpen(&tournament)
+	if tournament.Status != models.TournamentStatusOpen && !late {
 		return nil, ErrTournamentNotOpen
 	}
 
@@ -181,25 +440,37 @@ func (s *Service) Join(userID uuid.UUID, req *JoinRequest) (*models.TournamentPa
 	var participant *models.TournamentParticipant
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the tournament and check again, the scheduler may have started
+		// it or other players taken the last places since it was read
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, "id = ?", tournament.ID).Error; err != nil {
+			return err
+		}
+		late = lateRegistrationOpen(&tournament)
+		if tournament.Status != models.TournamentStatusOpen && !late {
+			return ErrTournamentNotOpen
+		}
+
+		var joined, count int64
+		if err := tx.Model(&models.TournamentParticipant{}).
+			Where("tournament_id = ? AND user_id = ?", tournament.ID, userID).
+			Count(&joined).Error; err != nil {
+			return err
+		}
+		if joined > 0 {
+			return ErrAlreadyJoined
+		}
+		if err := tx.Model(&models.TournamentParticipant{}).
+			Where("tournament_id = ?", tournament.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(tournament.MaxParticipants) {
+			return ErrTournamentFull
+		}
+
 		// Deduct entry fee
 		if tournament.EntryFee > 0 {
-			newBalance := user.Balance - tournament.EntryFee
-			if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-				return err
-			}
-
-			// Create transaction record
-			transaction := models.Transaction{
-				ID:            uuid.New(),
-				UserID:        userID,
-				Type:          models.TransactionTypeTournamentEntry,
-				Status:        models.TransactionStatusCompleted,
-				Amount:        -tournament.EntryFee,
-				ReferenceID:   &tournament.ID,
-				ReferenceType: strPtr("tournament"),
-				Description:   "Tournament entry: " + tournament.Name,
-			}
-			if err := tx.Create(&transaction).Error; err != nil {
+			if err := s.charge(tx, userID, &tournament, tournament.EntryFee, models.TransactionTypeTournamentEntry, "Tournament entry: "+tournament.Name); err != nil {
 				return err
 			}
 		}
@@ -212,6 +483,13 @@ func (s *Service) Join(userID uuid.UUID, req *JoinRequest) (*models.TournamentPa
 			Score:        0,
 			Rank:         0,
 			PrizeWon:     0,
+			TotalPaid:    tournament.EntryFee,
+			PoolPaid:     tournament.EntryFee * tournament.PoolShare,
+		}
+		// Late entrants only score from when they joined
+		if late {
+			now := time.Now()
+			participant.ScoringFrom = &now
 		}
 		return tx.Create(participant).Error
 	})
@@ -247,14 +525,29 @@ func (s *Service) Leave(userID uuid.UUID, tournamentID uuid.UUID) error {
 	}
 
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Refund entry fee
-		if tournament.EntryFee > 0 {
+		// Lock the tournament and check again so a start or a cancel can't
+		// run alongside, then re-read the entry for what was paid
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, "id = ?", tournament.ID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != models.TournamentStatusOpen && tournament.Status != models.TournamentStatusDraft {
+			return ErrTournamentNotOpen
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&participant, "id = ?", participant.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotParticipant
+			}
+			return err
+		}
+
+		// Refund what they paid and take back the share it added to the pool
+		if participant.TotalPaid > 0 {
 			var user models.User
 			if err := tx.First(&user, "id = ?", userID).Error; err != nil {
 				return err
 			}
 
-			newBalance := user.Balance + tournament.EntryFee
+			newBalance := user.Balance + participant.TotalPaid
 			if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
 				return err
 			}
@@ -265,7 +558,7 @@ func (s *Service) Leave(userID uuid.UUID, tournamentID uuid.UUID) error {
 				UserID:        userID,
 				Type:          models.TransactionTypeRefund,
 				Status:        models.TransactionStatusCompleted,
-				Amount:        tournament.EntryFee,
+				Amount:        participant.TotalPaid,
 				ReferenceID:   &tournament.ID,
 				ReferenceType: strPtr("tournament"),
 				Description:   "Tournament refund: " + tournament.Name,
@@ -273,6 +566,10 @@ func (s *Service) Leave(userID uuid.UUID, tournamentID uuid.UUID) error {
 			if err := tx.Create(&transaction).Error; err != nil {
 				return err
 			}
+
+			if err := tx.Model(&tournament).Update("prize_pool", gorm.Expr("prize_pool - ?", participant.PoolPaid)).Error; err != nil {
+				return err
+			}
 		}
 
 		// Remove participant
@@ -280,7 +577,7 @@ func (s *Service) Leave(userID uuid.UUID, tournamentID uuid.UUID) error {
 	})
 }
 
-// UpdateScore updates a participant's score (admin only or game service)
+// UpdateScore adds manual points to a participant's score (admin only)
 func (s *Service) UpdateScore(tournamentID uuid.UUID, req *UpdateScoreRequest) error {
 	var tournament models.Tournament
 	if err := s.db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
@@ -294,68 +591,38 @@ func (s *Service) UpdateScore(tournamentID uuid.UUID, req *UpdateScoreRequest) e
 		return ErrTournamentNotActive
 	}
 
-	result := s.db.Model(&models.TournamentParticipant{}).
-		Where("tournament_id = ? AND user_id = ?", tournamentID, req.UserID).
-		Update("score", gorm.Expr("score + ?", req.Score))
-
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrNotParticipant
-	}
-
-	return nil
-}
-
-// GetLeaderboard returns the tournament leaderboard
-func (s *Service) GetLeaderboard(tournamentID uuid.UUID) ([]LeaderboardEntry, error) {
-	var tournament models.Tournament
-	if err := s.db.Preload("Participants").First(&tournament, "id = ?", tournamentID).Error; err != nil {
+	var participant models.TournamentParticipant
+	if err := s.db.Where("tournament_id = ? AND user_id = ?", tournamentID, req.UserID).First(&participant).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrTournamentNotFound
+			return ErrNotParticipant
 		}
-		return nil, err
-	}
-
-	// Get user names
-	userIDs := make([]uuid.UUID, len(tournament.Participants))
-	for i, p := range tournament.Participants {
-		userIDs[i] = p.UserID
+		return err
 	}
 
-	var users []models.User
-	s.db.Where("id IN ?", userIDs).Find(&users)
-
-	userMap := make(map[uuid.UUID]string)
-	for _, u := range users {
-		userMap[u.ID] = u.Username
-	}
+	var change *RankChange
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&participant).Update("score_adjustment", gorm.Expr("score_adjustment + ?", req.Score)).Error; err != nil {
+			return err
+		}
 
-	// Sort by score descending
-	participants := tournament.Participants
-	sort.Slice(participants, func(i, j int) bool {
-		return participants[i].Score > participants[j].Score
+		var err error
+		change, err = rescore(tx, &participant, participant.Score+req.Score)
+		return err
 	})
-
-	leaderboard := make([]LeaderboardEntry, len(participants))
-	for i, p := range participants {
-		leaderboard[i] = LeaderboardEntry{
-			Rank:     i + 1,
-			UserID:   p.UserID,
-			UserName: userMap[p.UserID],
-			Score:    p.Score,
-			PrizeWon: p.PrizeWon,
-		}
+	if err != nil {
+		return err
 	}
 
-	return leaderboard, nil
+	if change != nil {
+		s.PublishRankChanges([]RankChange{*change})
+	}
+	return nil
 }
 
 // EndTournament ends tournament and distributes prizes (admin only)
 func (s *Service) EndTournament(tournamentID uuid.UUID) error {
 	var tournament models.Tournament
-	if err := s.db.Preload("Participants").First(&tournament, "id = ?", tournamentID).Error; err != nil {
+	if err := s.db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrTournamentNotFound
 		}
@@ -366,69 +633,67 @@ func (s *Service) EndTournament(tournamentID uuid.UUID) error {
 		return ErrTournamentNotActive
 	}
 
-	// Sort participants by score
-	participants := tournament.Participants
-	sort.Slice(participants, func(i, j int) bool {
-		return participants[i].Score > participants[j].Score
-	})
-
-	// Prize distribution (50% / 30% / 20% for top 3)
-	prizeDistribution := []float64{0.5, 0.3, 0.2}
-
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		for i, p := range participants {
-			if i >= len(prizeDistribution) {
-				break
-			}
-
-			prize := tournament.PrizePool * prizeDistribution[i]
-			if prize <= 0 {
-				continue
-			}
+		return s.endTournament(tx, &tournament)
+	})
+}
 
-			// Update participant prize
-			if err := tx.Model(&p).Updates(map[string]interface{}{
-				"rank":      i + 1,
-				"prize_won": prize,
-			}).Error; err != nil {
-				return err
-			}
+// endTournament ranks participants by score, pays out prizes and completes the tournament
+func (s *Service) endTournament(tx *gorm.DB, tournament *models.Tournament) error {
+	// Claim the tournament before paying anything, so a concurrent end or
+	// cancel finds it no longer in progress
+	result := tx.Model(&models.Tournament{}).
+		Where("id = ? AND status = ?", tournament.ID, models.TournamentStatusInProgress).
+		Update("status", models.TournamentStatusCompleted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTournamentNotActive
+	}
+	// Prize pool may have grown from rebuys since the tournament was loaded
+	if err := tx.First(tournament, "id = ?", tournament.ID).Error; err != nil {
+		return err
+	}
 
-			// Add prize to user balance
-			var user models.User
-			if err := tx.First(&user, "id = ?", p.UserID).Error; err != nil {
-				return err
-			}
+	if err := tx.Where("tournament_id = ?", tournament.ID).Find(&tournament.Participants).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("tournament_id = ?", tournament.ID).Find(&tournament.Prizes).Error; err != nil {
+		return err
+	}
 
-			newBalance := user.Balance + prize
-			if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-				return err
-			}
+	pool := currentPool(tournament)
+	placements := distribute(tournament.Participants, tournament.Prizes, pool)
 
-			// Create prize transaction
-			transaction := models.Transaction{
-				ID:            uuid.New(),
-				UserID:        p.UserID,
-				Type:          models.TransactionTypeTournamentPrize,
-				Status:        models.TransactionStatusCompleted,
-				Amount:        prize,
-				ReferenceID:   &tournament.ID,
-				ReferenceType: strPtr("tournament"),
-				Description:   "Tournament prize: " + tournament.Name,
-			}
-			if err := tx.Create(&transaction).Error; err != nil {
-				return err
-			}
+	for _, p := range placements {
+		if err := tx.Model(&p.participant).Updates(map[string]interface{}{
+			"rank":      p.rank,
+			"prize_won": p.prize,
+			"award":     p.award,
+		}).Error; err != nil {
+			return err
 		}
 
-		// Update remaining participant ranks
-		for i := len(prizeDistribution); i < len(participants); i++ {
-			tx.Model(&participants[i]).Update("rank", i+1)
+		if p.prize <= 0 {
+			continue
+		}
+		if err := s.credit(tx, p.participant.UserID, tournament, p.prize, models.TransactionTypeTournamentPrize, "Tournament prize: "+tournament.Name); err != nil {
+			return err
 		}
+	}
 
-		// Mark tournament as completed
-		return tx.Model(&tournament).Update("status", models.TournamentStatusCompleted).Error
-	})
+	// Record any house top-up to the guarantee
+	return tx.Model(tournament).Update("overlay", pool-tournament.PrizePool).Error
+}
+
+// currentPool is the prize pool as it stands, at least the guaranteed amount
+func currentPool(tournament *models.Tournament) float64 {
+	return math.Max(tournament.PrizePool, tournament.GuaranteedPool)
+}
+
+func tournamentTopic(tournamentID uuid.UUID) string {
+	return "tournament:" + tournamentID.String()
 }
 
 func strPtr(s string) *string {
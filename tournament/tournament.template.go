@@ -0,0 +1,348 @@
+package tournament
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxSpawnPerRun caps how many tournaments one template creates per
+// scheduler run, so a very frequent rule can't flood the table
+const maxSpawnPerRun = 50
+
+// GetTemplates returns every recurring tournament template (admin only)
+func (s *Service) GetTemplates() ([]models.TournamentTemplate, error) {
+	var templates []models.TournamentTemplate
+	if err := s.db.Order("name ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTemplate returns a recurring tournament template by ID (admin only)
+func (s *Service) GetTemplate(id uuid.UUID) (*models.TournamentTemplate, error) {
+	var template models.TournamentTemplate
+	if err := s.db.First(&template, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CreateTemplate creates a recurring tournament template (admin only)
+func (s *Service) CreateTemplate(req *TemplateRequest) (*models.TournamentTemplate, error) {
+	template := models.TournamentTemplate{
+		ID:               uuid.New(),
+		Name:             req.Name,
+		Description:      req.Description,
+		Recurrence:       req.Recurrence,
+		Duration:         req.Duration,
+		RegistrationLead: req.RegistrationLead,
+		GameID:           req.GameID,
+		Format:           models.TournamentFormatLeaderboard,
+		MatchRounds:      3,
+		ScoringMode:      models.ScoringModeTotalWinnings,
+		BestRounds:       req.BestRounds,
+		EntryFee:         req.EntryFee,
+		PrizePool:        req.PrizePool,
+		PoolShare:        s.config.PoolShare,
+		GuaranteedPool:   req.GuaranteedPool,
+		MaxParticipants:  req.MaxParticipants,
+		MinParticipants:  s.config.MinParticipants,
+		LateRegistration: req.LateRegistration,
+		RebuyLimit:       req.RebuyLimit,
+		RebuyFee:         req.RebuyFee,
+		RebuyMode:        models.RebuyModeReset,
+		RebuyScore:       req.RebuyScore,
+		AddOnFee:         req.AddOnFee,
+		AddOnScore:       req.AddOnScore,
+		Prizes:           templatePrizes(req.Prizes),
+	}
+	if req.Format != "" {
+		template.Format = req.Format
+	}
+	if req.MatchRounds > 0 {
+		template.MatchRounds = req.MatchRounds
+	}
+	if req.ScoringMode != "" {
+		template.ScoringMode = req.ScoringMode
+	}
+	if req.PoolShare != nil {
+		template.PoolShare = *req.PoolShare
+	}
+	if req.MinParticipants != nil {
+		template.MinParticipants = *req.MinParticipants
+	}
+	if req.RebuyMode != "" {
+		template.RebuyMode = req.RebuyMode
+	}
+
+	if err := validateTemplate(&template); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// UpdateTemplate edits a template. Tournaments it already spawned keep the
+// settings they were created with (admin only).
+func (s *Service) UpdateTemplate(id uuid.UUID, req *UpdateTemplateRequest) (*models.TournamentTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		template.Name = *req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Recurrence != nil {
+		template.Recurrence = *req.Recurrence
+	}
+	if req.Duration != nil {
+		template.Duration = *req.Duration
+	}
+	if req.RegistrationLead != nil {
+		template.RegistrationLead = *req.RegistrationLead
+	}
+	if req.IsPaused != nil {
+		template.IsPaused = *req.IsPaused
+	}
+	if req.GameID != nil {
+		template.GameID = req.GameID
+	}
+	if req.Format != nil {
+		template.Format = *req.Format
+	}
+	if req.MatchRounds != nil {
+		template.MatchRounds = *req.MatchRounds
+	}
+	if req.ScoringMode != nil {
+		template.ScoringMode = *req.ScoringMode
+	}
+	if req.BestRounds != nil {
+		template.BestRounds = *req.BestRounds
+	}
+	if req.EntryFee != nil {
+		template.EntryFee = *req.EntryFee
+	}
+	if req.PrizePool != nil {
+		template.PrizePool = *req.PrizePool
+	}
+	if req.PoolShare != nil {
+		template.PoolShare = *req.PoolShare
+	}
+	if req.GuaranteedPool != nil {
+		template.GuaranteedPool = *req.GuaranteedPool
+	}
+	if req.MaxParticipants != nil {
+		template.MaxParticipants = *req.MaxParticipants
+	}
+	if req.MinParticipants != nil {
+		template.MinParticipants = *req.MinParticipants
+	}
+	if req.LateRegistration != nil {
+		template.LateRegistration = *req.LateRegistration
+	}
+	if req.RebuyLimit != nil {
+		template.RebuyLimit = *req.RebuyLimit
+	}
+	if req.RebuyFee != nil {
+		template.RebuyFee = *req.RebuyFee
+	}
+	if req.RebuyMode != nil {
+		template.RebuyMode = *req.RebuyMode
+	}
+	if req.RebuyScore != nil {
+		template.RebuyScore = *req.RebuyScore
+	}
+	if req.AddOnFee != nil {
+		template.AddOnFee = *req.AddOnFee
+	}
+	if req.AddOnScore != nil {
+		template.AddOnScore = *req.AddOnScore
+	}
+	if req.Prizes != nil {
+		template.Prizes = templatePrizes(*req.Prizes)
+	}
+
+	if err := validateTemplate(template); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(template).Error; err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// SetTemplatePaused stops or resumes a template spawning tournaments. On
+// resume, occurrences missed while paused are skipped (admin only).
+func (s *Service) SetTemplatePaused(id uuid.UUID, paused bool) (*models.TournamentTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(template).Update("is_paused", paused).Error; err != nil {
+		return nil, err
+	}
+	template.IsPaused = paused
+	return template, nil
+}
+
+// DeleteTemplate deletes a template, leaving the tournaments it spawned (admin only)
+func (s *Service) DeleteTemplate(id uuid.UUID) error {
+	result := s.db.Delete(&models.TournamentTemplate{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// spawnFromTemplates creates a tournament for every occurrence of an active
+// template starting within SpawnAhead
+func (s *Service) spawnFromTemplates() {
+	var templates []models.TournamentTemplate
+	if err := s.db.Where("is_paused = ?", false).Find(&templates).Error; err != nil {
+		log.Printf("Error loading tournament templates: %v", err)
+		return
+	}
+
+	horizon := time.Now().Add(s.config.SpawnAhead)
+	for _, template := range templates {
+		if err := s.spawn(&template, horizon); err != nil {
+			log.Printf("Error spawning tournaments from template %s: %v", template.ID, err)
+		}
+	}
+}
+
+// spawn creates the template's tournaments starting up to horizon, after
+// the last one it created
+func (s *Service) spawn(template *models.TournamentTemplate, horizon time.Time) error {
+	sched, err := parseSchedule(template.Recurrence)
+	if err != nil {
+		return err
+	}
+
+	after := time.Now()
+	if template.LastStartsAt != nil && template.LastStartsAt.After(after) {
+		after = *template.LastStartsAt
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var last time.Time
+		startsAt := sched.next(after)
+		for n := 0; n < maxSpawnPerRun && !startsAt.IsZero() && !startsAt.After(horizon); n++ {
+			if _, err := s.create(tx, instanceRequest(template, startsAt), &template.ID); err != nil && !errors.Is(err, errAlreadySpawned) {
+				return err
+			}
+			last = startsAt
+			startsAt = sched.next(startsAt)
+		}
+
+		if last.IsZero() {
+			return nil
+		}
+		return tx.Model(template).Update("last_starts_at", last).Error
+	})
+}
+
+// instanceRequest builds the tournament a template spawns for one occurrence
+func instanceRequest(template *models.TournamentTemplate, startsAt time.Time) *CreateRequest {
+	registrationAt := time.Now()
+	if template.RegistrationLead > 0 {
+		registrationAt = startsAt.Add(-time.Duration(template.RegistrationLead) * time.Minute)
+	}
+
+	var lateRegistrationUntil *time.Time
+	if template.LateRegistration > 0 {
+		until := startsAt.Add(time.Duration(template.LateRegistration) * time.Minute)
+		lateRegistrationUntil = &until
+	}
+
+	prizes := make([]PrizeInput, len(template.Prizes))
+	for i, p := range template.Prizes {
+		prizes[i] = PrizeInput{
+			RankFrom:    p.RankFrom,
+			RankTo:      p.RankTo,
+			Type:        p.Type,
+			Amount:      p.Amount,
+			Description: p.Description,
+		}
+	}
+
+	return &CreateRequest{
+		Name:                  template.Name + " " + startsAt.Format("2006-01-02 15:04"),
+		Description:           template.Description,
+		GameID:                template.GameID,
+		Format:                template.Format,
+		MatchRounds:           template.MatchRounds,
+		ScoringMode:           template.ScoringMode,
+		BestRounds:            template.BestRounds,
+		EntryFee:              template.EntryFee,
+		PrizePool:             template.PrizePool,
+		PoolShare:             &template.PoolShare,
+		GuaranteedPool:        template.GuaranteedPool,
+		MaxParticipants:       template.MaxParticipants,
+		MinParticipants:       &template.MinParticipants,
+		RegistrationAt:        &registrationAt,
+		LateRegistrationUntil: lateRegistrationUntil,
+		RebuyLimit:            template.RebuyLimit,
+		RebuyFee:              template.RebuyFee,
+		RebuyMode:             template.RebuyMode,
+		RebuyScore:            template.RebuyScore,
+		AddOnFee:              template.AddOnFee,
+		AddOnScore:            template.AddOnScore,
+		StartsAt:              startsAt,
+		EndsAt:                startsAt.Add(time.Duration(template.Duration) * time.Minute),
+		Prizes:                prizes,
+	}
+}
+
+// validateTemplate checks a template would spawn valid tournaments
+func validateTemplate(template *models.TournamentTemplate) error {
+	if _, err := parseSchedule(template.Recurrence); err != nil {
+		return err
+	}
+	if template.ScoringMode == models.ScoringModeBestRounds && template.BestRounds <= 0 {
+		return ErrInvalidScoring
+	}
+	if template.Format == models.TournamentFormatKnockout && template.MinParticipants < 2 {
+		return ErrKnockoutTooSmall
+	}
+	if template.MaxParticipants < 1 {
+		return ErrNoPlaces
+	}
+	if len(template.Prizes) == 0 {
+		return nil
+	}
+	return validatePrizes(instanceRequest(template, time.Now()).Prizes, template.MaxParticipants)
+}
+
+func templatePrizes(prizes []PrizeInput) []models.TemplatePrize {
+	rows := make([]models.TemplatePrize, len(prizes))
+	for i, p := range prizes {
+		rows[i] = models.TemplatePrize{
+			RankFrom:    p.RankFrom,
+			RankTo:      p.RankTo,
+			Type:        p.Type,
+			Amount:      p.Amount,
+			Description: p.Description,
+		}
+	}
+	return rows
+}
@@ -19,8 +19,10 @@ func NewController(service *Service) *Controller {
 func (c *Controller) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/users/me", c.GetProfile)
 	r.PUT("/users/me", c.UpdateProfile)
+	r.GET("/users/me/tournaments", c.GetMyTournaments)
 	r.GET("/users/search", c.Search)
 	r.GET("/users/:id", c.GetByID)
+	r.GET("/users/:id/tournaments", c.GetTournaments)
 
 	r.GET("/friends", c.GetFriends)
 	r.GET("/friends/requests", c.GetPendingRequests)
@@ -168,6 +170,44 @@ func (c *Controller) Activate(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "user activated"})
 }
 
+func (c *Controller) GetMyTournaments(ctx *gin.Context) {
+	user := auth.GetClaims(ctx)
+
+	var filter TournamentHistoryFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter"})
+		return
+	}
+
+	history, err := c.service.GetTournamentHistory(user.UserID, &filter, false)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, history)
+}
+
+func (c *Controller) GetTournaments(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var filter TournamentHistoryFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter"})
+		return
+	}
+
+	history, err := c.service.GetTournamentHistory(id, &filter, true)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, history)
+}
+
 func handleError(ctx *gin.Context, err error) {
 	switch err {
 	case ErrUserNotFound:
@@ -1,6 +1,12 @@
 package user
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"gamba/models"
+
+	"github.com/google/uuid"
+)
 
 type UpdateProfileRequest struct {
 	Username *string `json:"username,omitempty"`
@@ -42,3 +48,42 @@ type FriendResponse struct {
 	Friend    *UserResponse `json:"friend,omitempty"`
 	CreatedAt string        `json:"created_at"`
 }
+
+type TournamentHistoryFilter struct {
+	Limit  int `form:"limit,default=20"`
+	Offset int `form:"offset,default=0"`
+}
+
+// TournamentEntry is one tournament a user played. Rank is live while the
+// tournament is running and final once it has completed.
+type TournamentEntry struct {
+	TournamentID uuid.UUID               `json:"tournament_id"`
+	Name         string                  `json:"name"`
+	Status       models.TournamentStatus `json:"status"`
+	Format       models.TournamentFormat `json:"format"`
+	StartsAt     time.Time               `json:"starts_at"`
+	EndsAt       time.Time               `json:"ends_at"`
+	Rank         int                     `json:"rank"`
+	Score        float64                 `json:"score"`
+	PrizeWon     float64                 `json:"prize_won"`
+	Award        string                  `json:"award,omitempty"`
+	TotalPaid    float64                 `json:"total_paid,omitempty"` // private
+	JoinedAt     time.Time               `json:"joined_at"`
+}
+
+// TournamentStats aggregates a user's completed tournaments. ITMRate is the
+// percentage that paid a prize and ROI the percentage return on what was paid
+// in; ROI is left out of the public view along with TotalPaid.
+type TournamentStats struct {
+	Played        int64    `json:"played"`
+	InTheMoney    int64    `json:"in_the_money"`
+	ITMRate       float64  `json:"itm_rate"`
+	TotalWinnings float64  `json:"total_winnings"`
+	TotalPaid     float64  `json:"total_paid,omitempty"`
+	ROI           *float64 `json:"roi,omitempty"`
+}
+
+type TournamentHistory struct {
+	Stats       TournamentStats   `json:"stats"`
+	Tournaments []TournamentEntry `json:"tournaments"`
+}
@@ -0,0 +1,63 @@
+package user
+
+import (
+	"gamba/models"
+
+	"github.com/google/uuid"
+)
+
+// GetTournamentHistory returns the tournaments a user has entered, newest
+// first, with stats over the completed ones. The public view hides what the
+// user paid in and their ROI.
+func (s *Service) GetTournamentHistory(userID uuid.UUID, filter *TournamentHistoryFilter, public bool) (*TournamentHistory, error) {
+	if _, err := s.GetByID(userID); err != nil {
+		return nil, err
+	}
+
+	history := &TournamentHistory{Tournaments: []TournamentEntry{}}
+
+	// Running tournaments have no stored rank yet, so count who is ahead
+	if err := s.db.Table("tournament_participants AS p").
+		Select("t.id AS tournament_id, t.name, t.status, t.format, t.starts_at, t.ends_at, "+
+			"p.score, p.prize_won, p.award, p.total_paid, p.joined_at, "+
+			"CASE WHEN t.status = ? THEN p.rank ELSE "+
+			"(SELECT COUNT(*) + 1 FROM tournament_participants AS o WHERE o.tournament_id = p.tournament_id AND o.score > p.score) END AS rank",
+			models.TournamentStatusCompleted).
+		Joins("JOIN tournaments AS t ON t.id = p.tournament_id AND t.deleted_at IS NULL").
+		Where("p.user_id = ?", userID).
+		Order("t.starts_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(&history.Tournaments).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Table("tournament_participants AS p").
+		Select("COUNT(*) AS played, "+
+			"COUNT(*) FILTER (WHERE p.prize_won > 0 OR COALESCE(p.award, '') <> '') AS in_the_money, "+
+			"COALESCE(SUM(p.prize_won), 0) AS total_winnings, "+
+			"COALESCE(SUM(p.total_paid), 0) AS total_paid").
+		Joins("JOIN tournaments AS t ON t.id = p.tournament_id AND t.deleted_at IS NULL").
+		Where("p.user_id = ? AND t.status = ?", userID, models.TournamentStatusCompleted).
+		Scan(&history.Stats).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &history.Stats
+	if stats.Played > 0 {
+		stats.ITMRate = float64(stats.InTheMoney) / float64(stats.Played) * 100
+	}
+	if stats.TotalPaid > 0 {
+		roi := (stats.TotalWinnings - stats.TotalPaid) / stats.TotalPaid * 100
+		stats.ROI = &roi
+	}
+
+	if public {
+		stats.TotalPaid = 0
+		stats.ROI = nil
+		for i := range history.Tournaments {
+			history.Tournaments[i].TotalPaid = 0
+		}
+	}
+	return history, nil
+}